@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/text/unicode/norm"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NormalizeAuthor returns name in Unicode NFKC form, with whitespace trimmed
+// and collapsed.
+func NormalizeAuthor(name string) string {
+	return strings.Join(strings.Fields(norm.NFKC.String(name)), " ")
+}
+
+// authorKey folds the differences between variants of the same name that
+// are never meaningful: Unicode form, whitespace and case.
+func authorKey(name string) string {
+	return strings.ToLower(NormalizeAuthor(name))
+}
+
+// An AliasTable maps variants of an author's name to their canonical name,
+// which serves as the author's ID.
+type AliasTable struct {
+	aliases map[string]string // authorKey(variant) -> canonical, per the alias file
+	learned map[string]string // authorKey(variant) -> most common variant
+}
+
+// Aliases canonicalizes every author credited on a review. It's loaded at
+// startup.
+var Aliases = NewAliasTable()
+
+func NewAliasTable() *AliasTable {
+	return &AliasTable{
+		aliases: map[string]string{},
+		learned: map[string]string{},
+	}
+}
+
+// LoadAliases reads an alias file: a JSON object mapping each canonical
+// name to a list of its variants. An empty filename yields an empty table.
+func LoadAliases(filename string) (*AliasTable, error) {
+	a := NewAliasTable()
+	if filename == "" {
+		return a, nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return a, err
+	}
+	defer f.Close()
+	m := map[string][]string{}
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return a, fmt.Errorf("%s: %s", filename, err)
+	}
+	for canonical, variants := range m {
+		canonical = NormalizeAuthor(canonical)
+		for _, variant := range append(variants, canonical) {
+			k := authorKey(variant)
+			if existing, ok := a.aliases[k]; ok && existing != canonical {
+				return a, fmt.Errorf(
+					"%s: %q is an alias of both %q and %q",
+					filename,
+					variant,
+					existing,
+					canonical,
+				)
+			}
+			a.aliases[k] = canonical
+		}
+	}
+	return a, nil
+}
+
+// Learn picks, for names not in the alias file, the most common variant of
+// each as canonical; ties go to the alphabetically first.
+func (a *AliasTable) Learn(names []string) {
+	counts := map[string]map[string]int{} // key -> variant -> count
+	for _, name := range names {
+		k, variant := authorKey(name), NormalizeAuthor(name)
+		if _, ok := counts[k]; !ok {
+			counts[k] = map[string]int{}
+		}
+		counts[k][variant]++
+	}
+	for k, variants := range counts {
+		best, bestCount := "", 0
+		for variant, count := range variants {
+			if count > bestCount || (count == bestCount && variant < best) {
+				best, bestCount = variant, count
+			}
+		}
+		a.learned[k] = best
+	}
+}
+
+// Canonical returns the canonical name of the author.
+func (a *AliasTable) Canonical(name string) string {
+	k := authorKey(name)
+	if canonical, ok := a.aliases[k]; ok {
+		return canonical
+	}
+	if canonical, ok := a.learned[k]; ok {
+		return canonical
+	}
+	return NormalizeAuthor(name)
+}
+
+//
+//
+//
+
+// A SuspectedDuplicate is a pair of canonical author names that are close
+// enough to be the same person.
+type SuspectedDuplicate struct {
+	A, B     string
+	Distance int
+}
+
+// SuspectedDuplicates returns every pair of names within maxDistance edits
+// of each other, ignoring case, closest first.
+func SuspectedDuplicates(names []string, maxDistance int) []SuspectedDuplicate {
+	sort.Strings(names)
+	dups := []SuspectedDuplicate{}
+	for i := 0; i < len(names); i++ {
+		for j := i + 1; j < len(names); j++ {
+			d := editDistance(authorKey(names[i]), authorKey(names[j]))
+			if d <= maxDistance {
+				dups = append(dups, SuspectedDuplicate{names[i], names[j], d})
+			}
+		}
+	}
+	sort.Stable(duplicatesByDistance(dups))
+	return dups
+}
+
+type duplicatesByDistance []SuspectedDuplicate
+
+func (a duplicatesByDistance) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a duplicatesByDistance) Len() int           { return len(a) }
+func (a duplicatesByDistance) Less(i, j int) bool { return a[i].Distance < a[j].Distance }
+
+// editDistance is the Levenshtein distance between a and b, in runes.
+// Differences in punctuation alone ("J.R." vs "JR") cost nothing.
+func editDistance(a, b string) int {
+	ra, rb := stripPunctuation(a), stripPunctuation(b)
+	prev, cur := make([]int, len(rb)+1), make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func stripPunctuation(s string) []rune {
+	runes := []rune{}
+	for _, r := range s {
+		if !unicode.IsPunct(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeAuthor(t *testing.T) {
+	names := map[string]string{
+		"Ian Cohen":                 "Ian Cohen",
+		"  Ian   Cohen ":            "Ian Cohen",
+		"Ian\u00a0Cohen":            "Ian Cohen",               // non-breaking space
+		"Jose\u0301 Gonza\u0301lez": "Jos\u00e9 Gonz\u00e1lez", // combining accents
+	}
+	for from, expected := range names {
+		if got := NormalizeAuthor(from); got != expected {
+			t.Errorf("%q: got %q, expected %q", from, got, expected)
+		}
+	}
+}
+
+func TestAliasTable(t *testing.T) {
+	filename := writeConfig(t, `{
+		"Mark Richardson": ["Mark  Richardson", "M. Richardson"]
+	}`)
+	defer os.Remove(filename)
+	a, err := LoadAliases(filename)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	a.Learn([]string{"Ian Cohen", "Ian Cohen", "ian cohen", "Ian  Cohen"})
+
+	names := map[string]string{
+		"m. richardson":   "Mark Richardson", // alias file
+		"Mark Richardson": "Mark Richardson",
+		"ian cohen":       "Ian Cohen", // most common variant
+		"IAN COHEN":       "Ian Cohen",
+		"Lindsay Zoladz":  "Lindsay Zoladz", // unknown
+	}
+	for from, expected := range names {
+		if got := a.Canonical(from); got != expected {
+			t.Errorf("%q: got %q, expected %q", from, got, expected)
+		}
+	}
+
+	conflicting := writeConfig(t, `{"A": ["X"], "B": ["x"]}`)
+	defer os.Remove(conflicting)
+	if _, err := LoadAliases(conflicting); err == nil || !strings.Contains(err.Error(), "alias of both") {
+		t.Errorf("expected conflicting alias error, got %v", err)
+	}
+}
+
+func TestAuthorsCanonicalized(t *testing.T) {
+	defer func(a *AliasTable) { Aliases = a }(Aliases)
+	Aliases = NewAliasTable()
+	Aliases.Learn([]string{"Ian Cohen", "Ian Cohen", "ian cohen"})
+
+	reviews := Reviews{
+		1: Review{ID: 1, Author: "Ian Cohen"},
+		2: Review{ID: 2, Author: "ian  cohen"},
+		3: Review{ID: 3, Author: "Ian Cohen & IAN COHEN"},
+	}
+	counts := reviews.AuthorCount()
+	if len(counts) != 1 || counts["Ian Cohen"] != 3 {
+		t.Errorf("got %v, expected 3 reviews by Ian Cohen", counts)
+	}
+}
+
+func TestSuspectedDuplicates(t *testing.T) {
+	dups := SuspectedDuplicates([]string{"Stuart Berman", "Stewart Berman", "Joe Tangari", "J.R. Smith", "JR Smith"}, 2)
+	if len(dups) != 2 {
+		t.Fatalf("got %v, expected 2 pairs", dups)
+	}
+	if dups[0].A != "J.R. Smith" || dups[0].B != "JR Smith" || dups[0].Distance != 0 {
+		t.Errorf("got %v, expected J.R. Smith ~ JR Smith at 0", dups[0])
+	}
+	if dups[1].A != "Stewart Berman" && dups[1].B != "Stewart Berman" || dups[1].Distance != 2 {
+		t.Errorf("got %v, expected Stuart ~ Stewart Berman at 2", dups[1])
+	}
+}
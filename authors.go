@@ -0,0 +1,97 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// reviewerSeparators split a byline like "A, B & C" or "A and B".
+var reviewerSeparators = regexp.MustCompile(`\s*(?:,\s*(?:and\s+|&\s*)?|\s&\s|\sand\s|\s\+\s|/)\s*`)
+
+// ParseReviewers splits a byline into the individual reviewers credited,
+// in order, without duplicates.
+func ParseReviewers(byline string) []string {
+	byline = strings.TrimSpace(byline)
+	if strings.HasPrefix(strings.ToLower(byline), "by ") {
+		byline = byline[3:]
+	}
+	reviewers, seen := []string{}, map[string]bool{}
+	for _, name := range reviewerSeparators.Split(byline, -1) {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		reviewers = append(reviewers, name)
+		seen[name] = true
+	}
+	return reviewers
+}
+
+// Authors returns the canonical names of the individual reviewers credited
+// in the review's byline.
+func (r Review) Authors() []string {
+	authors, seen := []string{}, map[string]bool{}
+	for _, name := range ParseReviewers(r.Author) {
+		canonical := Aliases.Canonical(name)
+		if !seen[canonical] {
+			authors = append(authors, canonical)
+			seen[canonical] = true
+		}
+	}
+	return authors
+}
+
+// RawAuthors returns every reviewer name credited, as written, once per
+// review.
+func (r Reviews) RawAuthors() []string {
+	names := []string{}
+	for _, review := range r {
+		names = append(names, ParseReviewers(review.Author)...)
+	}
+	return names
+}
+
+// AuthorScores estimates each author's score for every named index, per
+// shrink. A co-written review counts toward each co-author: fully, or, if
+// fractional, 1/n toward each of its n authors. "Reviews" is the number of
+// reviews each author is credited on.
+func (r Reviews) AuthorScores(indexNames []string, fractional bool) map[string]map[string]AuthorScore {
+	counts := map[string]int{}
+	samples := map[string]map[string]*authorSample{} // index name -> author -> sample
+	for _, indexName := range indexNames {
+		samples[indexName] = map[string]*authorSample{}
+	}
+	for _, review := range r {
+		authors := review.Authors()
+		weight := 1.0
+		if fractional {
+			weight = 1.0 / float64(len(authors))
+		}
+		for _, author := range authors {
+			counts[author]++
+			for _, indexName := range indexNames {
+				score, ok := review.Scores[indexName]
+				if !ok {
+					continue
+				}
+				s, ok := samples[indexName][author]
+				if !ok {
+					s = &authorSample{}
+					samples[indexName][author] = s
+				}
+				s.add(weight, score)
+			}
+		}
+	}
+
+	authors := map[string]map[string]AuthorScore{}
+	for author, count := range counts {
+		authors[author] = map[string]AuthorScore{"Reviews": exactly(float64(count))}
+	}
+	for indexName, byAuthor := range samples {
+		for author, score := range shrink(byAuthor) {
+			authors[author][indexName] = score
+		}
+	}
+	return authors
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseReviewers(t *testing.T) {
+	bylines := map[string][]string{
+		"Ian Cohen":                           []string{"Ian Cohen"},
+		"  Ian Cohen ":                        []string{"Ian Cohen"},
+		"Nitsuh Abebe & Tom Ewing":            []string{"Nitsuh Abebe", "Tom Ewing"},
+		"By Ryan Dombal and Ian Cohen":        []string{"Ryan Dombal", "Ian Cohen"},
+		"A, B & C":                            []string{"A", "B", "C"},
+		"A, B, and C":                         []string{"A", "B", "C"},
+		"Brandon Stosuy / Mark Richardson":    []string{"Brandon Stosuy", "Mark Richardson"},
+		"Amanda Petrusich & Amanda Petrusich": []string{"Amanda Petrusich"},
+		"":                                    []string{},
+	}
+	for byline, expected := range bylines {
+		got := ParseReviewers(byline)
+		if !equal(got, expected) {
+			t.Errorf("'%s': got %q, expected %q", byline, got, expected)
+		}
+	}
+}
+
+func TestAuthorScores(t *testing.T) {
+	reviews := Reviews{
+		1: Review{ID: 1, Author: "A", Scores: map[string]float64{"Foo": 10}},
+		2: Review{ID: 2, Author: "A & B", Scores: map[string]float64{"Foo": 40}},
+		3: Review{ID: 3, Author: "B", Scores: map[string]float64{"Foo": 20}},
+	}
+
+	full := reviews.AuthorScores([]string{"Foo"}, false)
+	if full["A"]["Reviews"].Score != 2 || full["B"]["Reviews"].Score != 2 {
+		t.Errorf("got review counts %v", full)
+	}
+	if full["A"]["Foo"].Mean != 25 || full["B"]["Foo"].Mean != 30 {
+		t.Errorf("full credit: got A %.2f, B %.2f; expected 25, 30", full["A"]["Foo"].Mean, full["B"]["Foo"].Mean)
+	}
+	if _, ok := full["A & B"]; ok {
+		t.Errorf("combined byline credited as an author")
+	}
+
+	// A: (10 + 0.5*40) / 1.5 = 20; B: (0.5*40 + 20) / 1.5 = 26.67
+	fractional := reviews.AuthorScores([]string{"Foo"}, true)
+	got := fmt.Sprintf("%.2f %.2f", fractional["A"]["Foo"].Mean, fractional["B"]["Foo"].Mean)
+	if got != "20.00 26.67" {
+		t.Errorf("fractional credit: got A, B %s; expected 20.00 26.67", got)
+	}
+}
@@ -1,21 +1,10 @@
 package main
 
-func calculateBullshit(review Review, allStats AllStatisticalData) int {
-	pf := DeviationsFromMinimum(
-		review.Scores["Pitchformulaity"],
-		allStats["Pitchformulaity"],
-	)
-	sl := DeviationsFromMinimum(
-		review.Scores["Sentence length"],
-		allStats["Sentence length"],
-	)
-	wc := DeviationsFromMinimum(
-		review.Scores["Word count"],
-		allStats["Word count"],
-	)
-	wi := DeviationsFromMinimum(
-		review.Scores["Words invented"],
-		allStats["Words invented"],
-	)
-	return 10*pf + 5*sl + 2*wc + 1*wi
+// DefaultBullshitWeights are the weights of each index in the Overall
+// Bullshit Score, absent any configuration.
+var DefaultBullshitWeights = map[string]int{
+	"Pitchformulaity": 10,
+	"Sentence length": 5,
+	"Word count":      2,
+	"Words invented":  1,
 }
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A Composite is a score defined as a weighted sum over other indexes. Each
+// index contributes its weight times the review's score for that index,
+// normalized against the distribution of all reviews' scores for it.
+type Composite struct {
+	Name           string
+	Weights        map[string]int           // index name -> weight
+	Normalizations map[string]Normalization // index name -> normalization; default per DefaultNormalization
+}
+
+// Validate checks that every index the Composite references is active.
+func (c Composite) Validate(indexes IndexMap) error {
+	if len(c.Weights) <= 0 {
+		return fmt.Errorf("composite %q references no indexes", c.Name)
+	}
+	for indexName, _ := range c.Weights {
+		if _, ok := indexes[indexName]; !ok {
+			return fmt.Errorf("composite %q references inactive or unknown index %q", c.Name, indexName)
+		}
+	}
+	return nil
+}
+
+func (c Composite) Score(review Review, allStats AllStatisticalData) float64 {
+	total := 0.0
+	for indexName, weight := range c.Weights {
+		total += float64(weight) * c.normalize(indexName, review.Scores[indexName], allStats[indexName])
+	}
+	return total
+}
+
+// normalize normalizes a score for the named index, negated if less is
+// worse, so that every index adds to the composite as it adds to Bullshit.
+func (c Composite) normalize(indexName string, score float64, stats StatisticalData) float64 {
+	normalized := c.normalization(indexName).Normalize(score, stats)
+	if idx, ok := LookupIndex(indexName); ok && idx.Direction == LessIsWorse {
+		return -normalized
+	}
+	return normalized
+}
+
+func (c Composite) normalization(indexName string) Normalization {
+	if n, ok := c.Normalizations[indexName]; ok {
+		return n
+	}
+	n, _ := LookupNormalization(DefaultNormalization)
+	return n
+}
+
+// definition describes the Composite, for the Provenance of its scores.
+func (c Composite) definition() string {
+	names := []string{}
+	for indexName, _ := range c.Weights {
+		names = append(names, indexName)
+	}
+	sort.Strings(names)
+	lines := []string{}
+	for _, indexName := range names {
+		idx, _ := LookupIndex(indexName)
+		lines = append(lines, fmt.Sprintf("%q %d %T %s", indexName, c.Weights[indexName], c.normalization(indexName), idx.Direction))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateComposites validates every Composite, and checks that none of
+// them shadows an index.
+func ValidateComposites(composites []Composite, indexes IndexMap) error {
+	for _, c := range composites {
+		if _, ok := indexes[c.Name]; ok {
+			return fmt.Errorf("composite %q has the same name as an index", c.Name)
+		}
+		if err := c.Validate(indexes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type compositesByName []Composite
+
+func (a compositesByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a compositesByName) Len() int           { return len(a) }
+func (a compositesByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDefaultCompositesValidate(t *testing.T) {
+	c, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	indexes, err := c.IndexDefinitions()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := ValidateComposites(c.CompositeDefinitions(), indexes); err != nil {
+		t.Errorf("%s", err)
+	}
+}
+
+func TestCompositeValidate(t *testing.T) {
+	indexes := IndexMap{"Word count": WordCount}
+	for _, c := range []Composite{
+		Composite{Name: "Empty", Weights: map[string]int{}},
+		Composite{Name: "Typo", Weights: map[string]int{"Sentence lenght": 5}},
+		Composite{Name: "Word count", Weights: map[string]int{"Word count": 1}},
+	} {
+		if err := ValidateComposites([]Composite{c}, indexes); err == nil {
+			t.Errorf("%s: expected error, got none", c.Name)
+		}
+	}
+}
+
+// Every component of the Overall Bullshit Score must move it: a review that
+// scores high on one component should out-Bullshit an otherwise-identical
+// review that scores low on it.
+func TestBullshitComponentsMoveComposite(t *testing.T) {
+	composite := Composite{Name: BullshitScore, Weights: DefaultBullshitWeights}
+	for component, _ := range composite.Weights {
+		reviews := Reviews{}
+		for i := 0; i < 20; i++ {
+			scores := map[string]float64{}
+			for indexName, _ := range composite.Weights {
+				scores[indexName] = float64(10 + i)
+			}
+			reviews[i] = Review{ID: i, Scores: scores}
+		}
+		allStats := AllStatisticalData{}
+		for indexName, _ := range composite.Weights {
+			allStats[indexName] = Gather(reviews, indexName)
+		}
+
+		low, high := Review{Scores: map[string]float64{}}, Review{Scores: map[string]float64{}}
+		for indexName, _ := range composite.Weights {
+			low.Scores[indexName] = 15
+			high.Scores[indexName] = 15
+		}
+		low.Scores[component] = 10
+		high.Scores[component] = 29
+		lowScore, highScore := composite.Score(low, allStats), composite.Score(high, allStats)
+		if highScore <= lowScore {
+			t.Errorf("%s: high score %.1f <= low score %.1f", component, highScore, lowScore)
+		}
+	}
+}
+
+// An index where less is worse must move a composite the other way: the
+// review that scores lower on it should out-Bullshit the one that scores
+// higher.
+func TestLessIsWorseMovesComposite(t *testing.T) {
+	RegisterIndex(Index{Name: "Plainness", Direction: LessIsWorse, New: Static(WordCount)})
+	defer delete(registry, "Plainness")
+
+	composite := Composite{Name: BullshitScore, Weights: map[string]int{"Plainness": 1, "Word count": 1}}
+	reviews := Reviews{}
+	for i := 0; i < 20; i++ {
+		reviews[i] = Review{ID: i, Scores: map[string]float64{"Plainness": float64(20 + 3*i), "Word count": 500}}
+	}
+	allStats := AllStatisticalData{}
+	for indexName, _ := range composite.Weights {
+		allStats[indexName] = Gather(reviews, indexName)
+	}
+	dense := Review{Scores: map[string]float64{"Plainness": 25, "Word count": 500}}
+	plain := Review{Scores: map[string]float64{"Plainness": 75, "Word count": 500}}
+	if denseScore, plainScore := composite.Score(dense, allStats), composite.Score(plain, allStats); denseScore <= plainScore {
+		t.Errorf("dense review scored %.2f, plain review %.2f; expected dense higher", denseScore, plainScore)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Config selects the active indexes, their parameters, and their weights in
+// the Overall Bullshit Score. Indexes not mentioned are active with default
+// parameters and weights. Composites defines additional composite scores,
+// or replaces the Overall Bullshit Score entirely.
+type Config struct {
+	Indexes    map[string]IndexConfig    `json:"indexes"`
+	Composites map[string]map[string]int `json:"composites"`
+}
+
+type IndexConfig struct {
+	Active        *bool  `json:"active"`        // default true
+	Weight        *int   `json:"weight"`        // default per DefaultBullshitWeights
+	Normalization string `json:"normalization"` // in every composite; default per DefaultNormalization
+	Params        Params `json:"params"`
+}
+
+func (ic IndexConfig) active() bool {
+	return ic.Active == nil || *ic.Active
+}
+
+// LoadConfig reads a JSON Config from filename. An empty filename yields the
+// default Config.
+func LoadConfig(filename string) (Config, error) {
+	c := Config{
+		Indexes:    map[string]IndexConfig{},
+		Composites: map[string]map[string]int{},
+	}
+	if filename == "" {
+		return c, nil
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		return c, err
+	}
+	defer f.Close()
+	d := json.NewDecoder(f)
+	d.DisallowUnknownFields()
+	if err := d.Decode(&c); err != nil {
+		return c, fmt.Errorf("%s: %s", filename, err)
+	}
+	if c.Indexes == nil {
+		c.Indexes = map[string]IndexConfig{}
+	}
+	if c.Composites == nil {
+		c.Composites = map[string]map[string]int{}
+	}
+	if err := c.Validate(); err != nil {
+		return c, fmt.Errorf("%s: %s", filename, err)
+	}
+	return c, nil
+}
+
+// Validate checks that every configured index is registered, accepts the
+// given parameters and normalization, and is active if it's given a weight,
+// and that every composite references only registered indexes.
+func (c Config) Validate() error {
+	for name, ic := range c.Indexes {
+		idx, ok := LookupIndex(name)
+		if !ok {
+			return fmt.Errorf("unknown index %q (known: %s)", name, registeredNames())
+		}
+		for k, _ := range ic.Params {
+			if _, ok := idx.Params[k]; !ok {
+				return fmt.Errorf("index %q: unknown parameter %q", name, k)
+			}
+		}
+		if _, ok := LookupNormalization(ic.Normalization); ic.Normalization != "" && !ok {
+			return fmt.Errorf("index %q: unknown normalization %q (known: %s)", name, ic.Normalization, normalizationNames())
+		}
+		if ic.Weight != nil && *ic.Weight != 0 && !ic.active() {
+			return fmt.Errorf("index %q is inactive but has weight %d", name, *ic.Weight)
+		}
+		if _, ok := c.Composites[BullshitScore]; ok && ic.Weight != nil {
+			return fmt.Errorf("index %q has a weight, but composite %q is defined explicitly", name, BullshitScore)
+		}
+	}
+	for name, weights := range c.Composites {
+		if _, ok := LookupIndex(name); ok {
+			return fmt.Errorf("composite %q has the same name as an index", name)
+		}
+		for indexName, _ := range weights {
+			if _, ok := LookupIndex(indexName); !ok {
+				return fmt.Errorf("composite %q: unknown index %q (known: %s)", name, indexName, registeredNames())
+			}
+		}
+	}
+	return nil
+}
+
+// IndexDefinitions builds the ScoringFunction of every active index.
+func (c Config) IndexDefinitions() (IndexMap, error) {
+	m := IndexMap{}
+	for _, idx := range RegisteredIndexes() {
+		ic := c.Indexes[idx.Name]
+		if !ic.active() {
+			continue
+		}
+		f, err := idx.Build(ic.Params)
+		if err != nil {
+			return m, err
+		}
+		m[idx.Name] = f
+	}
+	return m, nil
+}
+
+// Explainers builds the ExplainingFunction of every active index.
+func (c Config) Explainers() (ExplainerMap, error) {
+	m := ExplainerMap{}
+	for _, idx := range RegisteredIndexes() {
+		ic := c.Indexes[idx.Name]
+		if !ic.active() {
+			continue
+		}
+		e, err := idx.BuildExplainer(ic.Params)
+		if err != nil {
+			return m, err
+		}
+		m[idx.Name] = e
+	}
+	return m, nil
+}
+
+// Inputs returns the InputID of every active index that has one.
+func (c Config) Inputs() (map[string]string, error) {
+	m := map[string]string{}
+	for _, idx := range RegisteredIndexes() {
+		ic := c.Indexes[idx.Name]
+		if !ic.active() {
+			continue
+		}
+		id, err := idx.InputID(ic.Params)
+		if err != nil {
+			return m, err
+		}
+		if id != "" {
+			m[idx.Name] = id
+		}
+	}
+	return m, nil
+}
+
+// BullshitWeights returns DefaultBullshitWeights with configured weights
+// applied. Zero weights, and defaults for inactive indexes, are dropped.
+func (c Config) BullshitWeights() map[string]int {
+	weights := map[string]int{}
+	for name, weight := range DefaultBullshitWeights {
+		if c.Indexes[name].active() {
+			weights[name] = weight
+		}
+	}
+	for name, ic := range c.Indexes {
+		if ic.Weight != nil {
+			weights[name] = *ic.Weight
+		}
+	}
+	for name, weight := range weights {
+		if weight == 0 {
+			delete(weights, name)
+		}
+	}
+	return weights
+}
+
+// normalizations returns the configured Normalization of each index that
+// has one.
+func (c Config) normalizations() map[string]Normalization {
+	m := map[string]Normalization{}
+	for name, ic := range c.Indexes {
+		if n, ok := LookupNormalization(ic.Normalization); ok {
+			m[name] = n
+		}
+	}
+	return m
+}
+
+// CompositeDefinitions returns every composite score, sorted by name. The Overall
+// Bullshit Score is always among them.
+func (c Config) CompositeDefinitions() []Composite {
+	composites := []Composite{}
+	if _, ok := c.Composites[BullshitScore]; !ok {
+		composites = append(composites, Composite{
+			Name:           BullshitScore,
+			Weights:        c.BullshitWeights(),
+			Normalizations: c.normalizations(),
+		})
+	}
+	for name, weights := range c.Composites {
+		composites = append(composites, Composite{
+			Name:           name,
+			Weights:        weights,
+			Normalizations: c.normalizations(),
+		})
+	}
+	sort.Sort(compositesByName(composites))
+	return composites
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	f, err := ioutil.TempFile("", "pitchdex-config")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(contents); err != nil {
+		t.Fatalf("%s", err)
+	}
+	return f.Name()
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	configs := map[string]string{
+		`{"indexes": {"Bogosity": {}}}`:                               `unknown index "Bogosity"`,
+		`{"indexes": {"Word count": {"params": {"x": "1"}}}}`:         `unknown parameter "x"`,
+		`{"indexes": {"Word count": {"active": false, "weight": 3}}}`: `inactive but has weight 3`,
+		`{"indexs": {}}`: `unknown field "indexs"`,
+		`{"composites": {"X": {"Sentence count": 1}}}`:                                                            `composite "X": unknown index "Sentence count"`,
+		`{"composites": {"Word count": {"Word length": 1}}}`:                                                      `same name as an index`,
+		`{"indexes": {"Word count": {"weight": 1}}, "composites": {"Overall Bullshit Score": {"Word count": 1}}}`: `defined explicitly`,
+	}
+	for contents, expected := range configs {
+		filename := writeConfig(t, contents)
+		defer os.Remove(filename)
+		_, err := LoadConfig(filename)
+		if err == nil {
+			t.Errorf("%s: expected error, got none", contents)
+			continue
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: got '%s', expected '%s'", contents, err, expected)
+		}
+	}
+}
+
+func TestConfigIndexesAndWeights(t *testing.T) {
+	filename := writeConfig(t, `{"indexes": {
+		"Word count":      {"active": false},
+		"Pitchformulaity": {"weight": 20},
+		"Word length":     {"weight": 3},
+		"Words invented":  {"weight": 0, "params": {"dict": "testdata/words.txt"}}
+	}}`)
+	defer os.Remove(filename)
+	c, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	m, err := c.IndexDefinitions()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, ok := m["Word count"]; ok {
+		t.Errorf("inactive index 'Word count' was built")
+	}
+	if _, ok := m["Words invented"]; !ok {
+		t.Errorf("active index 'Words invented' wasn't built")
+	}
+	weights := c.BullshitWeights()
+	expected := map[string]int{"Pitchformulaity": 20, "Word length": 3}
+	for name, weight := range expected {
+		if weights[name] != weight {
+			t.Errorf("%s: got weight %d, expected %d", name, weights[name], weight)
+		}
+	}
+	for _, name := range []string{"Word count", "Words invented"} {
+		if _, ok := weights[name]; ok {
+			t.Errorf("%s: unexpected weight %d", name, weights[name])
+		}
+	}
+}
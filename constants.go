@@ -1,36 +1,30 @@
 package main
 
 var (
+	// PitchformulaWords are weighed by their stems, so each word stands for
+	// all of its forms: "croon" for "croons" and "crooning" too.
 	PitchformulaWords = map[string]int{
 		// word          triteness (higher=more trite)
 		"warm":          1,
 		"warmth":        1,
-		"distorted":     1,
 		"distortion":    1,
-		"echoes":        1,
-		"echoing":       1,
 		"echo":          1,
-		"organic":       5,
 		"percussive":    1,
 		"gentle":        1,
 		"heavy":         1,
 		"soft":          1,
 		"lush":          9,
-		"ethereal":      9,
 		"delicate":      3,
 		"plucked":       1,
 		"buzzing":       1,
 		"shimmering":    5,
 		"fragile":       5,
 		"swirling":      1,
-		"cutting":       1,
 		"understated":   2,
 		"clouds":        1,
 		"chiming":       1,
-		"pounding":      1,
 		"pulsing":       1,
 		"fluid":         1,
-		"rolling":       1,
 		"skittering":    3,
 		"rumbling":      1,
 		"dreamy":        1,
@@ -42,18 +36,13 @@ var (
 		"subtly":        2,
 		"subtlety":      2,
 		"layer":         1,
-		"layers":        1,
-		"layered":       1,
-		"builds":        1,
 		"swells":        1,
 		"crescendos":    1,
 		"rising":        1,
 		"blast":         1,
-		"blasts":        1,
 		"crashing":      1,
 		"explosive":     1,
 		"complex":       1,
-		"complexity":    1,
 		"complicated":   1,
 		"simple":        1,
 		"massive":       1,
@@ -66,19 +55,15 @@ var (
 		"chaotic":       1,
 		"dense":         1,
 		"structure":     1,
-		"structured":    1,
 		"abstract":      1,
-		"accessible":    1,
 		"detail":        1,
 		"detailed":      1,
 		"seamlessly":    1,
 		"hypnotic":      4,
 		"shifting":      1,
 		"drifting":      1,
-		"drops":         1,
 		"sky":           1,
 		"storm":         1,
-		"storms":        1,
 		"stormy":        1,
 		"twists":        1,
 		"dynamic":       1,
@@ -87,27 +72,19 @@ var (
 		"sweeping":      1,
 		"surreal":       1,
 		"dissonance":    1,
-		"glowing":       3,
 		"vibrant":       1,
-		"controlled":    1,
 		"faded":         1,
-		"winds":         1,
 		"skeletal":      5,
 		"repeatedly":    1,
 		"glow":          1,
 		"spacious":      1,
 		"ocean":         1,
-		"oceans":        1,
-		"oceanic":       1,
 		"rough":         1,
 		"primitive":     1,
 		"lone":          1,
-		"dominated":     1,
 		"unstructured":  1,
 		"rehearsed":     1,
-		"polished":      1,
 		"shiny":         1,
-		"predictable":   1,
 		"melancholy":    3,
 		"sadness":       1,
 		"plaintive":     1,
@@ -120,7 +97,6 @@ var (
 		"frantic":       2,
 		"frenzied":      1,
 		"wild":          1,
-		"madness":       1,
 		"crazed":        1,
 		"strange":       1,
 		"mysterious":    2,
@@ -136,20 +112,13 @@ var (
 		"ominous":       1,
 		"menacing":      1,
 		"frightening":   1,
-		"tense":         1,
 		"anxiety":       1,
 		"anxious":       1,
 		"restless":      1,
 		"furious":       1,
 		"fury":          1,
 		"anger":         1,
-		"emotional":     1,
-		"playful":       1,
-		"personal":      1,
-		"affecting":     1,
-		"assured":       1,
 		"confidence":    1,
-		"confident":     1,
 		"romantic":      1,
 		"relentless":    1,
 		"despair":       2,
@@ -159,32 +128,95 @@ var (
 		"modest":        1,
 		"dangerous":     1,
 		"tortured":      1,
-		"alienation":    2,
 		"insecurity":    1,
 		"whisper":       1,
-		"whispers":      1,
-		"whispering":    1,
 		"croon":         5,
-		"croons":        5,
-		"crooning":      5,
 		"wail":          3,
-		"wails":         3,
-		"wailing":       3,
 		"tenor":         1,
 		"chant":         1,
-		"chants":        1,
-		"chanting":      1,
 		"baritone":      1,
 		"soprano":       1,
 		"alto":          1,
 		"choir":         1,
 		"scream":        1,
-		"screams":       1,
-		"screaming":     1,
 		"off-key":       1,
 		"yell":          1,
-		"yelling":       1,
 		"nasal":         1,
-		"nasally":       1,
+	}
+
+	// PitchformulaForms are weighed only in exactly these forms, because
+	// their stems are common words in their own right: "cutting" is trite,
+	// "cut" is a track.
+	PitchformulaForms = map[string]int{
+		"organic":     5,
+		"ethereal":    9,
+		"cutting":     1,
+		"rolling":     1,
+		"builds":      1,
+		"accessible":  1,
+		"drops":       1,
+		"glowing":     3,
+		"controlled":  1,
+		"winds":       1,
+		"polished":    1,
+		"predictable": 1,
+		"madness":     1,
+		"tense":       1,
+		"playful":     1,
+		"personal":    1,
+		"alienation":  2,
+		"pounding":    1,
+		"dominated":   1,
+		"emotional":   1,
+		"affecting":   1,
+		"assured":     1,
+	}
+
+	PitchformulaLexicon = mustLexicon(PitchformulaWords, PitchformulaForms)
+
+	// ClichePhrases are matched word by word, by stem, so "walls of sound"
+	// is "wall of sound".
+	ClichePhrases = map[string]int{
+		// phrase                                 triteness
+		"sonic palette":                          5,
+		"sonic landscape":                        4,
+		"sonic tapestry":                         5,
+		"wall of sound":                          3,
+		"sophomore slump":                        5,
+		"return to form":                         4,
+		"wears its influences on its sleeve":     6,
+		"wear their influences on their sleeves": 6,
+		"heart on its sleeve":                    4,
+		"heart on their sleeves":                 4,
+		"tour de force":                          3,
+		"labor of love":                          3,
+		"greater than the sum of its parts":      4,
+		"less than the sum of its parts":         4,
+		"breath of fresh air":                    3,
+		"rough around the edges":                 2,
+		"slow burn":                              3,
+		"instant classic":                        3,
+		"ear for melody":                         2,
+		"more of the same":                       2,
+		"change of pace":                         2,
+		"everything but the kitchen sink":        3,
+		"paint-by-numbers":                       2,
+		"genre-defying":                          3,
+		"coming-of-age":                          2,
+	}
+
+	// GenreWords are music words that general dictionaries miss. They're
+	// never invented.
+	GenreWords = []string{
+		"alt-country", "americana", "b-side", "bandcamp", "breakbeat",
+		"chillwave", "chiptune", "darkwave", "dancehall", "downtempo",
+		"dream-pop", "drone", "dubstep", "edm", "electroclash",
+		"electronica", "emo", "freak-folk", "glitch", "grime", "grindcore",
+		"hauntology", "hi-fi", "hip-hop", "idm", "indie", "juke",
+		"krautrock", "lo-fi", "math-rock", "metalcore", "mixtape", "motorik",
+		"noise-pop", "noise-rock", "nu-metal", "outro", "overdub",
+		"post-hardcore", "post-punk", "post-rock", "psych", "reverb", "riff",
+		"riffage", "screamo", "shoegaze", "slowcore", "sludge", "synth",
+		"synthpop", "trap", "trip-hop", "vaporwave", "witch-house",
 	}
 )
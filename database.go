@@ -5,73 +5,196 @@ import (
 	"fmt"
 	_ "github.com/mattn/go-sqlite3"
 	"strings"
+	"time"
 )
 
-func GetDB(filename string) (*sql.DB, error) {
-	return sql.Open("sqlite3", filename)
+// A DB is a SQL database and the dialect it speaks. Queries are written
+// with ? placeholders, and rewritten as needed for the dialect.
+type DB struct {
+	*sql.DB
+	dialect dialect
 }
 
-func Initialize(db *sql.DB) error {
-	statements := []string{
-		"CREATE TABLE reviews (id INT PRIMARY KEY, body TEXT)",
-		"CREATE TABLE authors (name TEXT PRIMARY KEY)",
-		"CREATE TABLE review_scores (review_id INT, name STRING, score INT)",
-		"CREATE TABLE author_scores (author_name STRING, name STRING, score INT)",
-		"CREATE TABLE authorship (review_id INT, author_name STRING)",
-		"CREATE INDEX review_score_name ON review_scores (name)",
-		"CREATE INDEX author_score_name ON author_scores (name)",
-		"CREATE INDEX review_score_nsc ON review_scores (name, score)",
-		"CREATE INDEX author_score_nsc ON author_scores (name, score)",
-	}
-	for _, statement := range statements {
-		db.Exec(statement) // Best-effort is.. best.. effort.
+type dialect int
+
+const (
+	sqliteDialect dialect = iota
+	postgresDialect
+)
+
+// GetDB opens a SQLite database.
+func GetDB(filename string) (*DB, error) {
+	db, err := sql.Open("sqlite3", filename)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &DB{db, sqliteDialect}, nil
 }
 
-func InsertReview(db *sql.DB, review Review) error {
-	_, err := db.Exec(
-		"INSERT INTO reviews VALUES (?, ?)",
-		review.ID,
-		review.Body,
-	)
-	if err != nil {
-		return err
+func (db *DB) rebind(query string) string {
+	if db.dialect == postgresDialect {
+		return dollarPlaceholders(query)
 	}
+	return query
+}
 
-	// best-effort is OK: author may exist
-	db.Exec(
-		"INSERT INTO authors VALUES (?)",
-		review.Author,
-	)
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.rebind(query), args...)
+}
 
-	_, err = db.Exec(
-		"INSERT INTO authorship VALUES (?, ?)",
-		review.ID,
-		review.Author,
-	)
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.rebind(query), args...)
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.rebind(query), args...)
+}
+
+// Initialize brings the database schema up to date.
+func Initialize(db *DB) error {
+	_, _, err := Migrate(db)
+	return err
+}
+
+// withTx runs f inside a transaction, which is committed only if f succeeds.
+func withTx(db *DB, f func(*sql.Tx) error) error {
+	tx, err := db.Begin()
 	if err != nil {
 		return err
 	}
-	scoreMap := map[int]map[string]int{
-		review.ID: review.Scores,
-	}
-	if err := InsertReviewScores(db, scoreMap, true); err != nil {
+	if err := f(tx); err != nil {
+		tx.Rollback()
 		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
-func InsertReviews(db *sql.DB, reviews Reviews) error {
-	for _, review := range reviews {
-		if err := InsertReview(db, review); err != nil {
-			return err
+// prepareAll prepares every query on tx, or none of them.
+func prepareAll(db *DB, tx *sql.Tx, queries ...string) ([]*sql.Stmt, error) {
+	stmts := make([]*sql.Stmt, len(queries))
+	for i, query := range queries {
+		stmt, err := tx.Prepare(db.rebind(query))
+		if err != nil {
+			closeAll(stmts[:i])
+			return nil, err
 		}
+		stmts[i] = stmt
 	}
-	return nil
+	return stmts, nil
+}
+
+func closeAll(stmts []*sql.Stmt) {
+	for _, stmt := range stmts {
+		stmt.Close()
+	}
+}
+
+func InsertReview(db *DB, review Review) error {
+	return InsertReviews(db, Reviews{review.ID: review})
 }
 
-func SelectBody(db *sql.DB, id int) (string, error) {
+// InsertReviews upserts every review, its authors, authorship and scores in
+// a single transaction. On error, the database is left untouched.
+func InsertReviews(db *DB, reviews Reviews) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		stmts, err := prepareAll(
+			db,
+			tx,
+			`INSERT INTO reviews (
+				id, body, permalink, artist, album, label,
+				release_year, published, rating, best_new_music, byline
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET
+				body = excluded.body,
+				permalink = excluded.permalink,
+				artist = excluded.artist,
+				album = excluded.album,
+				label = excluded.label,
+				release_year = excluded.release_year,
+				published = excluded.published,
+				rating = excluded.rating,
+				best_new_music = excluded.best_new_music,
+				byline = excluded.byline`,
+			"INSERT INTO authors VALUES (?) ON CONFLICT (name) DO NOTHING",
+			"DELETE FROM authorship WHERE review_id = ?",
+			`INSERT INTO authorship VALUES (?, ?)
+			 ON CONFLICT (review_id, author_name) DO NOTHING`,
+			upsertReviewScore,
+		)
+		if err != nil {
+			return err
+		}
+		defer closeAll(stmts)
+		upsertReview, upsertAuthor, deleteAuthorship, upsertAuthorship, upsertScore :=
+			stmts[0], stmts[1], stmts[2], stmts[3], stmts[4]
+
+		for _, review := range reviews {
+			if _, err := upsertReview.Exec(
+				review.ID,
+				review.Body,
+				review.Permalink,
+				review.Artist,
+				review.Album,
+				review.Label,
+				review.ReleaseYear,
+				formatDate(review.Published),
+				review.Rating,
+				review.BestNewMusic,
+				review.Author,
+			); err != nil {
+				return fmt.Errorf("review %d: %s", review.ID, err)
+			}
+			if _, err := deleteAuthorship.Exec(review.ID); err != nil {
+				return fmt.Errorf("review %d: authorship: %s", review.ID, err)
+			}
+			for _, author := range review.Authors() {
+				if _, err := upsertAuthor.Exec(author); err != nil {
+					return fmt.Errorf("review %d: author: %s", review.ID, err)
+				}
+				if _, err := upsertAuthorship.Exec(review.ID, author); err != nil {
+					return fmt.Errorf("review %d: authorship: %s", review.ID, err)
+				}
+			}
+			for scoreName, scoreValue := range review.Scores {
+				p := review.Provenance[scoreName]
+				if _, err := upsertScore.Exec(review.ID, scoreName, scoreValue, p.Version, p.Input, p.Hash); err != nil {
+					return fmt.Errorf("review %d: score %q: %s", review.ID, scoreName, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+const (
+	upsertReviewScore = `INSERT INTO review_scores
+		(review_id, name, score, version, input, hash)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (review_id, name) DO UPDATE SET
+			score = excluded.score,
+			version = excluded.version,
+			input = excluded.input,
+			hash = excluded.hash`
+	insertReviewScore = `INSERT INTO review_scores
+		(review_id, name, score, version, input, hash)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (review_id, name) DO NOTHING`
+	upsertAuthorScore = `INSERT INTO author_scores
+		(author_name, name, score, mean, low, high, computed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (author_name, name) DO UPDATE SET
+			score = excluded.score,
+			mean = excluded.mean,
+			low = excluded.low,
+			high = excluded.high,
+			computed_at = excluded.computed_at`
+	insertAuthorScore = `INSERT INTO author_scores
+		(author_name, name, score, mean, low, high, computed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (author_name, name) DO NOTHING`
+)
+
+func SelectBody(db *DB, id int) (string, error) {
 	row := db.QueryRow("SELECT body FROM reviews WHERE id = ?", id)
 	if row == nil {
 		return "", fmt.Errorf("fail to query")
@@ -83,153 +206,391 @@ func SelectBody(db *sql.DB, id int) (string, error) {
 	return s, nil
 }
 
-func SelectBodys(db *sql.DB, ids []int) (map[int]string, error) {
-	m := map[int]string{}
-	strs := make([]string, len(ids))
-	for i, id := range ids {
-		strs[i] = fmt.Sprintf("%d", id)
-	}
-	clause := strings.Join(strs, ",")
-	rows, err := db.Query(
-		fmt.Sprintf(
-			"SELECT id, body FROM reviews WHERE id IN (%s)",
-			clause,
-		),
-	)
-	if err != nil {
-		return m, err
+// selectBatchSize bounds the number of placeholders in one statement, well
+// under SQLite's historical limit of 999.
+const selectBatchSize = 500
+
+// placeholders returns "?,?,...?" with n placeholders.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// batches splits ids into slices of at most selectBatchSize, as query args.
+func batches(ids []int) [][]interface{} {
+	all := [][]interface{}{}
+	for len(ids) > 0 {
+		n := selectBatchSize
+		if len(ids) < n {
+			n = len(ids)
+		}
+		args := make([]interface{}, n)
+		for i, id := range ids[:n] {
+			args[i] = id
+		}
+		all = append(all, args)
+		ids = ids[n:]
 	}
-	for rows.Next() {
-		var id int
-		var body string
-		if err := rows.Scan(&id, &body); err != nil {
-			return m, fmt.Errorf("SELECT error: %s", err)
+	return all
+}
+
+func SelectBodys(db *DB, ids []int) (map[int]string, error) {
+	m := map[int]string{}
+	for _, args := range batches(ids) {
+		rows, err := db.Query(
+			fmt.Sprintf(
+				"SELECT id, body FROM reviews WHERE id IN (%s)",
+				placeholders(len(args)),
+			),
+			args...,
+		)
+		if err != nil {
+			return m, err
+		}
+		for rows.Next() {
+			var id int
+			var body string
+			if err := rows.Scan(&id, &body); err != nil {
+				rows.Close()
+				return m, fmt.Errorf("SELECT error: %s", err)
+			}
+			m[id] = body
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return m, err
 		}
-		m[id] = body
 	}
 	return m, nil
 }
 
-func SelectReviews(db *sql.DB, ids []int) (Reviews, error) {
+func SelectReviews(db *DB, ids []int) (Reviews, error) {
 	reviews := Reviews{}
-	strs := make([]string, len(ids))
-	for i, id := range ids {
-		strs[i] = fmt.Sprintf("%d", id)
+	for _, args := range batches(ids) {
+		if err := selectReviewBatch(db, args, reviews); err != nil {
+			return reviews, err
+		}
 	}
-	clause := strings.Join(strs, ",")
+	return reviews, nil
+}
+
+func selectReviewBatch(db *DB, args []interface{}, reviews Reviews) error {
 	rows, err := db.Query(
 		fmt.Sprintf(
-			`SELECT r.id, a.name, r.body
-			 FROM reviews r, authors a, authorship x
-			 WHERE r.id IN (%s)
-			 AND x.review_id == r.id
-			 AND x.author_name == a.name
+			`SELECT id, byline, body, permalink, artist, album, label,
+			        release_year, published, rating, best_new_music
+			 FROM reviews
+			 WHERE id IN (%s)
 			`,
-			clause,
+			placeholders(len(args)),
 		),
+		args...,
 	)
 	if err != nil {
-		return reviews, err
+		return err
 	}
+	defer rows.Close()
 	for rows.Next() {
-		var id int
-		var author string
-		var body string
-		if err := rows.Scan(&id, &author, &body); err != nil {
-			return reviews, fmt.Errorf("SELECT review error: %s", err)
+		var review Review
+		var published string
+		if err := rows.Scan(
+			&review.ID,
+			&review.Author,
+			&review.Body,
+			&review.Permalink,
+			&review.Artist,
+			&review.Album,
+			&review.Label,
+			&review.ReleaseYear,
+			&published,
+			&review.Rating,
+			&review.BestNewMusic,
+		); err != nil {
+			return fmt.Errorf("SELECT review error: %s", err)
 		}
-		reviews[id] = Review{
-			ID:     id,
-			Author: author,
-			Body:   body,
-			Scores: map[string]int{},
+		if review.Published, err = parseDate(published); err != nil {
+			return fmt.Errorf("review %d: %s", review.ID, err)
 		}
+		review.Scores = map[string]float64{}
+		review.Provenance = map[string]Provenance{}
+		reviews[review.ID] = review
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
 	rows, err = db.Query(
 		fmt.Sprintf(
-			`SELECT review_id, name, score
+			`SELECT review_id, name, score, version, input, hash
 			 FROM review_scores
 			 WHERE review_id IN (%s)
 			`,
-			clause,
+			placeholders(len(args)),
 		),
+		args...,
 	)
 	if err != nil {
-		return reviews, err
+		return err
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var id int
 		var scoreName string
-		var scoreValue int
-		if err := rows.Scan(&id, &scoreName, &scoreValue); err != nil {
-			return reviews, fmt.Errorf("SELECT score error: %s", err)
+		var scoreValue float64
+		var p Provenance
+		if err := rows.Scan(&id, &scoreName, &scoreValue, &p.Version, &p.Input, &p.Hash); err != nil {
+			return fmt.Errorf("SELECT score error: %s", err)
+		}
+		if _, ok := reviews[id]; ok {
+			reviews[id].Scores[scoreName] = scoreValue
+			reviews[id].Provenance[scoreName] = p
 		}
-		reviews[id].Scores[scoreName] = scoreValue
 	}
-	return reviews, nil
+	return rows.Err()
 }
 
-func SelectAllReviews(db *sql.DB) (Reviews, error) {
+func SelectAllReviews(db *DB) (Reviews, error) {
 	reviews := Reviews{}
-	ids := []int{}
-	rows, err := db.Query("SELECT id FROM reviews")
+	it := IterateReviews(db)
+	for it.Next() {
+		review := it.Review()
+		reviews[review.ID] = review
+	}
+	return reviews, it.Err()
+}
+
+// A ReviewIterator streams reviews in ID order.
+type ReviewIterator interface {
+	Next() bool
+	Review() Review
+	Err() error
+}
+
+// IterateReviews streams every review in the database, a batch at a time,
+// so the whole archive never needs to be in memory at once.
+func IterateReviews(db *DB) ReviewIterator {
+	return &sqlReviewIterator{db: db, i: -1}
+}
+
+type sqlReviewIterator struct {
+	db      *DB
+	batch   []Review
+	i       int
+	started bool
+	lastID  int
+	done    bool
+	err     error
+}
+
+func (it *sqlReviewIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	it.i++
+	for it.i >= len(it.batch) {
+		if it.done {
+			return false
+		}
+		it.batch, it.i = it.batch[:0], 0
+		if it.err = it.fetch(); it.err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (it *sqlReviewIterator) fetch() error {
+	query := "SELECT id FROM reviews ORDER BY id LIMIT ?"
+	args := []interface{}{selectBatchSize}
+	if it.started {
+		query = "SELECT id FROM reviews WHERE id > ? ORDER BY id LIMIT ?"
+		args = []interface{}{it.lastID, selectBatchSize}
+	}
+	it.started = true
+	rows, err := it.db.Query(query, args...)
 	if err != nil {
-		return reviews, err
+		return err
 	}
+	ids := []int{}
 	for rows.Next() {
 		var id int
 		if err := rows.Scan(&id); err != nil {
-			return reviews, err
+			rows.Close()
+			return err
 		}
 		ids = append(ids, id)
 	}
-	return SelectReviews(db, ids)
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(ids) < selectBatchSize {
+		it.done = true
+	}
+	if len(ids) <= 0 {
+		return nil
+	}
+	it.lastID = ids[len(ids)-1]
+	reviews, err := SelectReviews(it.db, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if review, ok := reviews[id]; ok {
+			it.batch = append(it.batch, review)
+		}
+	}
+	return nil
 }
 
-func InsertReviewScores(db *sql.DB, scores map[int]map[string]int, overwrite bool) error {
-	for reviewId, scoreMap := range scores {
-		for scoreName, scoreValue := range scoreMap {
-			if overwrite {
-				db.Exec(
-					"DELETE FROM review_scores WHERE review_id = ? AND name = ?",
-					reviewId,
-					scoreName,
-				)
-			}
-			_, err := db.Exec(
-				"INSERT INTO review_scores VALUES (?, ?, ?)",
-				reviewId,
-				scoreName,
-				scoreValue,
-			)
-			if err != nil {
-				return err
+func (it *sqlReviewIterator) Review() Review { return it.batch[it.i] }
+func (it *sqlReviewIterator) Err() error     { return it.err }
+
+// InsertReviewScores writes scores in a single transaction, without
+// Provenance. Existing scores are replaced only if overwrite is set.
+func InsertReviewScores(db *DB, scores map[int]map[string]float64, overwrite bool) error {
+	query := insertReviewScore
+	if overwrite {
+		query = upsertReviewScore
+	}
+	return withTx(db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(db.rebind(query))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for reviewId, scoreMap := range scores {
+			for scoreName, scoreValue := range scoreMap {
+				if _, err := stmt.Exec(reviewId, scoreName, scoreValue, 0, "", ""); err != nil {
+					return err
+				}
 			}
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
-func InsertAuthorScores(db *sql.DB, scores map[string]map[string]int, overwrite bool) error {
-	for authorName, scoreMap := range scores {
-		for scoreName, scoreValue := range scoreMap {
-			if overwrite {
-				db.Exec(
-					"DELETE FROM author_scores WHERE author_name = ? AND name = ?",
-					authorName,
-					scoreName,
-				)
+// InsertAuthorScores writes scores in a single transaction, stamped with the
+// current time. Existing scores are replaced only if overwrite is set.
+func InsertAuthorScores(db *DB, scores map[string]map[string]AuthorScore, overwrite bool) error {
+	query := insertAuthorScore
+	if overwrite {
+		query = upsertAuthorScore
+	}
+	computedAt := time.Now().UTC().Format(time.RFC3339)
+	return withTx(db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(db.rebind(query))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+		for authorName, scoreMap := range scores {
+			for scoreName, s := range scoreMap {
+				if _, err := stmt.Exec(authorName, scoreName, s.Score, s.Mean, s.Low, s.High, computedAt); err != nil {
+					return err
+				}
 			}
-			_, err := db.Exec(
-				"INSERT INTO author_scores VALUES (?, ?, ?)",
-				authorName,
-				scoreName,
-				scoreValue,
-			)
-			if err != nil {
+		}
+		return nil
+	})
+}
+
+// PruneAuthors deletes authors no longer credited on any review, e.g.
+// variants since aliased to a canonical name, along with their scores.
+func PruneAuthors(db *DB) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		for _, statement := range []string{
+			"DELETE FROM authors WHERE name NOT IN (SELECT author_name FROM authorship)",
+			"DELETE FROM author_scores WHERE author_name NOT IN (SELECT name FROM authors)",
+		} {
+			if _, err := tx.Exec(statement); err != nil {
 				return err
 			}
 		}
+		return nil
+	})
+}
+
+func SelectAuthorScores(db *DB, names []string) (map[string]map[string]AuthorScore, error) {
+	authors := map[string]map[string]AuthorScore{}
+	for len(names) > 0 {
+		n := selectBatchSize
+		if len(names) < n {
+			n = len(names)
+		}
+		args := make([]interface{}, n)
+		for i, name := range names[:n] {
+			args[i] = name
+		}
+		names = names[n:]
+		if err := selectAuthorScoreBatch(db, args, authors); err != nil {
+			return authors, err
+		}
 	}
-	return nil
+	return authors, nil
+}
+
+func selectAuthorScoreBatch(db *DB, args []interface{}, authors map[string]map[string]AuthorScore) error {
+	rows, err := db.Query(
+		fmt.Sprintf(
+			`SELECT author_name, name, score, mean, low, high
+			 FROM author_scores
+			 WHERE author_name IN (%s)
+			`,
+			placeholders(len(args)),
+		),
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var author string
+		var scoreName string
+		var s AuthorScore
+		if err := rows.Scan(&author, &scoreName, &s.Score, &s.Mean, &s.Low, &s.High); err != nil {
+			return fmt.Errorf("SELECT author score error: %s", err)
+		}
+		if _, ok := authors[author]; !ok {
+			authors[author] = map[string]AuthorScore{}
+		}
+		authors[author][scoreName] = s
+	}
+	return rows.Err()
+}
+
+func SelectAllAuthorScores(db *DB) (map[string]map[string]AuthorScore, error) {
+	names := []string{}
+	rows, err := db.Query("SELECT DISTINCT author_name FROM author_scores")
+	if err != nil {
+		return map[string]map[string]AuthorScore{}, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return map[string]map[string]AuthorScore{}, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return map[string]map[string]AuthorScore{}, err
+	}
+	return SelectAuthorScores(db, names)
+}
+
+// AuthorScoresComputedAt returns when author scores were last written; the
+// zero Time if never.
+func AuthorScoresComputedAt(db *DB) (time.Time, error) {
+	var s string
+	row := db.QueryRow("SELECT COALESCE(MAX(computed_at), '') FROM author_scores")
+	if err := row.Scan(&s); err != nil {
+		return time.Time{}, err
+	}
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
 }
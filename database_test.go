@@ -1,6 +1,8 @@
 package main
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"testing"
 )
@@ -33,7 +35,7 @@ func TestInsertSelect(t *testing.T) {
 		Body:      "This is the review body.",
 		Author:    "Joe Reviewer",
 		Permalink: "123-foo-bar",
-		Scores:    map[string]int{"Foo": 7},
+		Scores:    map[string]float64{"Foo": 7},
 	}
 	if err := InsertReview(db, r1); err != nil {
 		t.Fatalf("%s", err)
@@ -54,7 +56,7 @@ func TestInsertSelect(t *testing.T) {
 		Body:      "Second body.",
 		Author:    "Frank Reviewer",
 		Permalink: "456-baz",
-		Scores:    map[string]int{},
+		Scores:    map[string]float64{},
 	}
 	if err := InsertReview(db, r2); err != nil {
 		t.Fatalf("%s", err)
@@ -96,11 +98,475 @@ func TestInsertSelect(t *testing.T) {
 		t.Errorf("got '%s', expected '%s'", review123.Author, r1.Author)
 	}
 	if review123.Scores["Foo"] != r1.Scores["Foo"] {
-		t.Errorf("got %d, expected %d", review123.Scores["Foo"], r1.Scores["Foo"])
+		t.Errorf("got %.2f, expected %.2f", review123.Scores["Foo"], r1.Scores["Foo"])
 	} else {
-		t.Logf("%v: Foo score was %d", review123, review123.Scores["Foo"])
+		t.Logf("%v: Foo score was %.2f", review123, review123.Scores["Foo"])
 	}
 }
 
 func TestScoring(t *testing.T) {
 }
+
+func TestMigrate(t *testing.T) {
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	// A database created before schema_version existed
+	if _, err := db.Exec("CREATE TABLE reviews (id INT PRIMARY KEY, body TEXT)"); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if v, err := SchemaVersion(db); err != nil || v != 0 {
+		t.Fatalf("got version %d (%v), expected 0", v, err)
+	}
+
+	from, to, err := Migrate(db)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if from != 0 || to != TargetSchemaVersion() {
+		t.Errorf("migrated %d -> %d, expected 0 -> %d", from, to, TargetSchemaVersion())
+	}
+
+	// Idempotent
+	from, to, err = Migrate(db)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if from != to || to != TargetSchemaVersion() {
+		t.Errorf("migrated %d -> %d, expected no-op at %d", from, to, TargetSchemaVersion())
+	}
+
+	// Newer than this binary
+	if _, err := db.Exec(
+		"INSERT INTO schema_version VALUES (?, 'future', '')",
+		TargetSchemaVersion()+1,
+	); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, _, err := Migrate(db); err == nil {
+		t.Errorf("expected error migrating a newer schema, got none")
+	}
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	for i, m := range Migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d (%s) at position %d", m.Version, m.Description, i)
+		}
+	}
+}
+
+func TestReviewRoundTrip(t *testing.T) {
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := Initialize(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	f, err := ioutil.TempFile("", "pitchdex-import")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`[{
+		"reviewers": "Joe Reviewer",
+		"editorial": "<p>Lush, ethereal.</p>",
+		"key": "17001-some-album",
+		"artist": "Some Band",
+		"album": "Some Album",
+		"label": "Some Label",
+		"year": 2012,
+		"pub_date": "2012-10-02",
+		"score": 8.4,
+		"best_new_music": true
+	}]`)
+	f.Close()
+	imported := Reviews{}
+	if err := imported.ImportJSON(f.Name(), false); err != nil {
+		t.Fatalf("%s", err)
+	}
+	r1, ok := imported[17001]
+	if !ok {
+		t.Fatalf("review 17001 not imported: %v", imported)
+	}
+	if err := InsertReview(db, r1); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	reviews, err := SelectReviews(db, []int{r1.ID})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	r2 := reviews[r1.ID]
+	for _, c := range []struct {
+		column    string
+		got, want interface{}
+	}{
+		{"id", r2.ID, 17001},
+		{"author", r2.Author, "Joe Reviewer"},
+		{"body", r2.Body, "<p>Lush, ethereal.</p>"},
+		{"permalink", r2.Permalink, "17001-some-album"},
+		{"artist", r2.Artist, "Some Band"},
+		{"album", r2.Album, "Some Album"},
+		{"label", r2.Label, "Some Label"},
+		{"release_year", r2.ReleaseYear, 2012},
+		{"published", formatDate(r2.Published), "2012-10-02"},
+		{"rating", r2.Rating, 8.4},
+		{"best_new_music", r2.BestNewMusic, true},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s: got %v, expected %v", c.column, c.got, c.want)
+		}
+	}
+}
+
+func TestInsertReviewsIdempotent(t *testing.T) {
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := Initialize(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	reviews := Reviews{
+		1: Review{ID: 1, Author: "A", Body: "One.", Scores: map[string]float64{"Foo": 1}},
+		2: Review{ID: 2, Author: "B", Body: "Two.", Scores: map[string]float64{"Foo": 2}},
+	}
+	if err := InsertReviews(db, reviews); err != nil {
+		t.Fatalf("first run: %s", err)
+	}
+	reviews[2] = Review{ID: 2, Author: "C", Body: "Two, again.", Scores: map[string]float64{"Foo": 3}}
+	if err := InsertReviews(db, reviews); err != nil {
+		t.Fatalf("second run: %s", err)
+	}
+
+	got, err := SelectReviews(db, []int{1, 2})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got[2].Author != "C" || got[2].Body != "Two, again." || got[2].Scores["Foo"] != 3 {
+		t.Errorf("review 2 not updated: %v", got[2])
+	}
+	for table, expected := range map[string]int{
+		"reviews":       2,
+		"authorship":    2,
+		"review_scores": 2,
+	} {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil {
+			t.Fatalf("%s", err)
+		}
+		if n != expected {
+			t.Errorf("%s: got %d rows, expected %d", table, n, expected)
+		}
+	}
+}
+
+func TestInsertReviewsAtomic(t *testing.T) {
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := Initialize(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := InsertReview(db, Review{ID: 1, Author: "A", Body: "Before."}); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	// Simulate a failure partway through the batch
+	if _, err := db.Exec(
+		`CREATE TRIGGER fail BEFORE INSERT ON reviews WHEN NEW.id = 3
+		 BEGIN SELECT RAISE(ABORT, 'simulated failure'); END`,
+	); err != nil {
+		t.Fatalf("%s", err)
+	}
+	reviews := Reviews{
+		1: Review{ID: 1, Author: "A", Body: "After."},
+		2: Review{ID: 2, Author: "B", Body: "Two."},
+		3: Review{ID: 3, Author: "C", Body: "Three."},
+	}
+	if err := InsertReviews(db, reviews); err == nil {
+		t.Fatalf("expected simulated failure, got none")
+	}
+
+	body, err := SelectBody(db, 1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if body != "Before." {
+		t.Errorf("review 1: got '%s', expected 'Before.'", body)
+	}
+	if _, err := SelectBody(db, 2); err == nil {
+		t.Errorf("review 2 written despite failed batch")
+	}
+}
+
+func TestAuthorScoresRoundTrip(t *testing.T) {
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := Initialize(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if computedAt, err := AuthorScoresComputedAt(db); err != nil || !computedAt.IsZero() {
+		t.Errorf("got computed-at %v (%v), expected zero", computedAt, err)
+	}
+
+	authors := map[string]map[string]AuthorScore{
+		"Joe Reviewer":   map[string]AuthorScore{"Reviews": exactly(3), "Foo": AuthorScore{7, 8, 6.5, 9.5}},
+		"Frank Reviewer": map[string]AuthorScore{"Reviews": exactly(1), "Foo": exactly(2)},
+	}
+	if err := InsertAuthorScores(db, authors, true); err != nil {
+		t.Fatalf("%s", err)
+	}
+	authors["Frank Reviewer"]["Foo"] = AuthorScore{4, 5, 1, 9}
+	if err := InsertAuthorScores(db, authors, true); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	got, err := SelectAllAuthorScores(db)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for author, scores := range authors {
+		for scoreName, score := range scores {
+			if got[author][scoreName] != score {
+				t.Errorf("%s %s: got %+v, expected %+v", author, scoreName, got[author][scoreName], score)
+			}
+		}
+	}
+	one, err := SelectAuthorScores(db, []string{"Joe Reviewer"})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(one) != 1 || one["Joe Reviewer"]["Foo"].Score != 7 {
+		t.Errorf("got %v, expected only Joe Reviewer", one)
+	}
+	if computedAt, err := AuthorScoresComputedAt(db); err != nil || computedAt.IsZero() {
+		t.Errorf("got computed-at %v (%v), expected non-zero", computedAt, err)
+	}
+}
+
+func TestSelectManyReviews(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow")
+	}
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := Initialize(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	const n = 100000
+	reviews, ids := Reviews{}, make([]int, n)
+	for i := 0; i < n; i++ {
+		id := 1000000 + i
+		reviews[id] = Review{
+			ID:     id,
+			Author: fmt.Sprintf("Reviewer %d", i%200),
+			Body:   fmt.Sprintf("Review number %d.", i),
+			Scores: map[string]float64{"Foo": float64(i)},
+		}
+		ids[i] = id
+	}
+	if err := InsertReviews(db, reviews); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	bodys, err := SelectBodys(db, ids)
+	if err != nil {
+		t.Fatalf("SelectBodys: %s", err)
+	}
+	if len(bodys) != n {
+		t.Errorf("SelectBodys: got %d, expected %d", len(bodys), n)
+	}
+
+	all, err := SelectAllReviews(db)
+	if err != nil {
+		t.Fatalf("SelectAllReviews: %s", err)
+	}
+	if len(all) != n {
+		t.Errorf("SelectAllReviews: got %d, expected %d", len(all), n)
+	}
+	if r := all[1000000+n-1]; r.Scores["Foo"] != n-1 || r.Author != "Reviewer 199" {
+		t.Errorf("last review: got %v", r)
+	}
+
+	count, last := 0, 0
+	it := IterateReviews(db)
+	for it.Next() {
+		if id := it.Review().ID; id <= last {
+			t.Fatalf("IterateReviews: %d after %d", id, last)
+		} else {
+			last = id
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("IterateReviews: %s", err)
+	}
+	if count != n {
+		t.Errorf("IterateReviews: got %d, expected %d", count, n)
+	}
+}
+
+func TestCoAuthoredReview(t *testing.T) {
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := Initialize(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+	r := Review{ID: 1, Author: "Nitsuh Abebe & Tom Ewing", Body: "Two voices."}
+	if err := InsertReview(db, r); err != nil {
+		t.Fatalf("%s", err)
+	}
+	reviews, err := SelectReviews(db, []int{1})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if reviews[1].Author != r.Author {
+		t.Errorf("got byline '%s', expected '%s'", reviews[1].Author, r.Author)
+	}
+	for _, author := range []string{"Nitsuh Abebe", "Tom Ewing"} {
+		var n int
+		if err := db.QueryRow(
+			"SELECT COUNT(*) FROM authorship WHERE review_id = 1 AND author_name = ?",
+			author,
+		).Scan(&n); err != nil {
+			t.Fatalf("%s", err)
+		}
+		if n != 1 {
+			t.Errorf("%s: got %d authorship rows, expected 1", author, n)
+		}
+	}
+}
+
+func TestSplitAuthorshipMigration(t *testing.T) {
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err := SchemaVersion(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, m := range Migrations[:4] {
+		if err := applyMigration(db, m); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	for _, statement := range []string{
+		"INSERT INTO reviews (id, body) VALUES (1, 'One.')",
+		"INSERT INTO authors VALUES ('A & B')",
+		"INSERT INTO authorship VALUES (1, 'A & B')",
+		"INSERT INTO author_scores VALUES ('A & B', 'Foo', 1, '')",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %s", statement, err)
+		}
+	}
+	if _, _, err := Migrate(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	reviews, err := SelectReviews(db, []int{1})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if reviews[1].Author != "A & B" {
+		t.Errorf("got byline '%s', expected 'A & B'", reviews[1].Author)
+	}
+	for query, expected := range map[string]int{
+		"SELECT COUNT(*) FROM authorship WHERE author_name IN ('A', 'B')": 2,
+		"SELECT COUNT(*) FROM authorship WHERE author_name = 'A & B'":     0,
+		"SELECT COUNT(*) FROM authors":                                    2,
+		"SELECT COUNT(*) FROM author_scores":                              0,
+	} {
+		var n int
+		if err := db.QueryRow(query).Scan(&n); err != nil {
+			t.Fatalf("%s", err)
+		}
+		if n != expected {
+			t.Errorf("%s: got %d, expected %d", query, n, expected)
+		}
+	}
+}
+
+func TestRealScoresMigration(t *testing.T) {
+	os.Remove("testing.db")
+	db, err := GetDB("testing.db")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, err := SchemaVersion(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, m := range Migrations[:5] {
+		if err := applyMigration(db, m); err != nil {
+			t.Fatalf("%s", err)
+		}
+	}
+	for _, statement := range []string{
+		"INSERT INTO reviews (id, body, byline) VALUES (1, 'One.', 'A')",
+		"INSERT INTO authors VALUES ('A')",
+		"INSERT INTO authorship VALUES (1, 'A')",
+		"INSERT INTO review_scores VALUES (1, 'Foo', 7)",
+		"INSERT INTO author_scores VALUES ('A', 'Foo', 7, '2013-01-02T03:04:05Z')",
+	} {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %s", statement, err)
+		}
+	}
+	if _, _, err := Migrate(db); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	for query, expected := range map[string]string{
+		"SELECT typeof(score) FROM review_scores": "real",
+		"SELECT typeof(score) FROM author_scores": "real",
+		"SELECT computed_at FROM author_scores":   "2013-01-02T03:04:05Z",
+	} {
+		var got string
+		if err := db.QueryRow(query).Scan(&got); err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got != expected {
+			t.Errorf("%s: got '%s', expected '%s'", query, got, expected)
+		}
+	}
+	authors, err := SelectAllAuthorScores(db)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if authors["A"]["Foo"] != exactly(7) {
+		t.Errorf("got author score %+v, expected exactly 7", authors["A"]["Foo"])
+	}
+	if err := InsertReviewScores(db, map[int]map[string]float64{1: {"Bar": 4.9}}, false); err != nil {
+		t.Fatalf("%s", err)
+	}
+	reviews, err := SelectReviews(db, []int{1})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if reviews[1].Scores["Foo"] != 7 || reviews[1].Scores["Bar"] != 4.9 {
+		t.Errorf("got scores %v, expected Foo 7, Bar 4.9", reviews[1].Scores)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+)
+
+// BuiltinDictionary names the word list built into pitchdex. Its words are
+// only ever added to in a new version, with a new name, so that scores
+// computed with one version can be told from scores computed with another.
+const BuiltinDictionary = "english-1"
+
+//go:embed words/english-1.txt
+var builtinWords []byte
+
+// DictInfo identifies a dictionary: by name, and by the hash of its
+// contents, since a file of the same name may differ between machines.
+type DictInfo struct {
+	Name string
+	Hash string // SHA-256, hex
+}
+
+func (i DictInfo) String() string {
+	return fmt.Sprintf("%s@sha256:%.16s", i.Name, i.Hash)
+}
+
+func dictInfo(name string, data []byte) DictInfo {
+	sum := sha256.Sum256(data)
+	return DictInfo{name, hex.EncodeToString(sum[:])}
+}
+
+// dictionaryFiles holds every dictionary file read, by filename. Scores
+// record the hash of a file, and are computed with its words, which are
+// read separately; both come from the same bytes, even if the file changes
+// in between.
+var dictionaryFiles = struct {
+	sync.Mutex
+	data map[string][]byte
+}{data: map[string][]byte{}}
+
+// readDictionary returns the contents of a dictionary file, and its
+// DictInfo. An empty filename, or "builtin", is the BuiltinDictionary. A
+// file is only read once.
+func readDictionary(filename string) ([]byte, DictInfo, error) {
+	if filename == "" || filename == "builtin" {
+		return builtinWords, dictInfo(BuiltinDictionary, builtinWords), nil
+	}
+	dictionaryFiles.Lock()
+	defer dictionaryFiles.Unlock()
+	data, ok := dictionaryFiles.data[filename]
+	if !ok {
+		var err error
+		if data, err = ioutil.ReadFile(filename); err != nil {
+			return nil, DictInfo{}, err
+		}
+		dictionaryFiles.data[filename] = data
+	}
+	return data, dictInfo(filepath.Base(filename), data), nil
+}
+
+// LoadDictionary is like NewDict, but an empty filename, or "builtin", is
+// the BuiltinDictionary, and it returns the DictInfo of what it loaded.
+func LoadDictionary(filename string) (Dict, DictInfo, error) {
+	d := Dict{}
+	info, err := d.LoadDictionary(filename)
+	if err != nil {
+		return nil, info, err
+	}
+	if d.Count() <= 0 {
+		return nil, info, fmt.Errorf("%s: no words", info.Name)
+	}
+	return d, info, nil
+}
+
+// LoadDictionary adds the words of a dictionary file to d, as read by
+// DictionaryInfo.
+func (d Dict) LoadDictionary(filename string) (DictInfo, error) {
+	data, info, err := readDictionary(filename)
+	if err != nil {
+		return info, err
+	}
+	return info, d.Read(bytes.NewReader(data))
+}
+
+// DictionaryInfo returns the DictInfo of a dictionary without loading it.
+func DictionaryInfo(filename string) (DictInfo, error) {
+	_, info, err := readDictionary(filename)
+	return info, err
+}
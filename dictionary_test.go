@@ -0,0 +1,117 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinDictionary(t *testing.T) {
+	dict, info, err := LoadDictionary("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != BuiltinDictionary || len(info.Hash) != 64 {
+		t.Errorf("got %+v, expected %s and a SHA-256", info, BuiltinDictionary)
+	}
+	if other, _ := DictionaryInfo("builtin"); other != info {
+		t.Errorf("got %+v, expected %+v", other, info)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(builtinWords)), "\n") {
+		if line != strings.ToLower(strings.TrimSpace(line)) || line == "" {
+			t.Errorf("%q: expected a lowercase word", line)
+		}
+	}
+	for _, word := range strings.Fields(
+		"the guitars were shimmering and the drummer's unpredictably loud fills " +
+			"couldn't save an otherwise forgettable album of songs she wrote herself " +
+			"percussive skittering sonorous cavernous angular serrated mellifluous",
+	) {
+		if !dict.Known(word) {
+			t.Errorf("%q: expected a known word", word)
+		}
+	}
+}
+
+// The words the lexicons score are ordinary English, and mustn't count as
+// invented too.
+func TestBuiltinDictionaryLexicons(t *testing.T) {
+	dict, _, err := LoadDictionary("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	words := []string{}
+	for word, _ := range PitchformulaWords {
+		words = append(words, word)
+	}
+	for word, _ := range PitchformulaForms {
+		words = append(words, word)
+	}
+	for phrase, _ := range ClichePhrases {
+		words = append(words, strings.Fields(phrase)...)
+	}
+	for _, word := range words {
+		if !dict.Known(word) {
+			t.Errorf("%q: expected a known word", word)
+		}
+	}
+}
+
+func TestLoadDictionary(t *testing.T) {
+	dict, info, err := LoadDictionary("testdata/words.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "words.txt" || !dict.Has("guitar") || dict.Has("orchestra") {
+		t.Errorf("got %v from %+v, expected testdata/words.txt", dict, info)
+	}
+	builtin, _ := DictionaryInfo("")
+	if info.Hash == builtin.Hash {
+		t.Errorf("expected the file and the built-in list to hash differently")
+	}
+	if _, _, err := LoadDictionary("testdata/nonexistent.txt"); err == nil {
+		t.Errorf("expected error for a missing dictionary")
+	}
+}
+
+// A dictionary's DictInfo is of the words loaded from it, even if the file
+// changes in between.
+func TestDictionaryReadOnce(t *testing.T) {
+	filename := writeConfig(t, "guitar\n")
+	defer os.Remove(filename)
+	dict, info, err := LoadDictionary(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte("orchestra\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := DictionaryInfo(filename); err != nil || again != info {
+		t.Errorf("got %+v, %v; expected %+v", again, err, info)
+	}
+	if !dict.Has("guitar") {
+		t.Errorf("got %v, expected guitar", dict)
+	}
+}
+
+func TestInventedWordsInput(t *testing.T) {
+	idx, _ := LookupIndex("Words invented")
+	builtin, err := idx.InputID(Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(builtin, BuiltinDictionary+"@sha256:") {
+		t.Errorf("got %q, expected the built-in dictionary", builtin)
+	}
+	file, err := idx.InputID(Params{"dict": "testdata/words.txt", "whitelist": "testdata/words.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(file, "words.txt@") || !strings.Contains(file, " + words.txt@") {
+		t.Errorf("got %q, expected the dictionary and the whitelist", file)
+	}
+	if _, err := idx.InputID(Params{"dict": "testdata/nonexistent.txt"}); err == nil {
+		t.Errorf("expected error for a missing dictionary")
+	}
+}
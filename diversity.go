@@ -0,0 +1,142 @@
+package main
+
+import (
+	"math"
+)
+
+// Lexical diversity measures how many different words a text uses: a
+// reviewer working through a thesaurus scores high. The simplest measure,
+// the type-token ratio, falls as texts get longer, since common words
+// recur; MTLD and HD-D correct for length.
+
+// frequencies counts each type (distinct word) among tokens.
+func frequencies(tokens []string) map[string]int {
+	freq := map[string]int{}
+	for _, tok := range tokens {
+		freq[tok]++
+	}
+	return freq
+}
+
+// TypeTokenRatio is the number of distinct words over the number of words.
+func TypeTokenRatio(tokens []string) float64 {
+	if len(tokens) <= 0 {
+		return 0
+	}
+	return float64(len(frequencies(tokens))) / float64(len(tokens))
+}
+
+// mtldThreshold is the type-token ratio at which MTLD ends a factor.
+const mtldThreshold = 0.72
+
+// MTLD is the measure of textual lexical diversity: the mean length of the
+// runs of words it takes the type-token ratio to fall to 0.72, read both
+// forwards and backwards. A text that never gets that repetitive scores
+// its length.
+// McCarthy and Jarvis, "MTLD, vocd-D, and HD-D" (2010)
+func MTLD(tokens []string) float64 {
+	if len(tokens) <= 0 {
+		return 0
+	}
+	reversed := make([]string, len(tokens))
+	for i, tok := range tokens {
+		reversed[len(tokens)-1-i] = tok
+	}
+	return (mtldPass(tokens) + mtldPass(reversed)) / 2
+}
+
+func mtldPass(tokens []string) float64 {
+	factors, types, n := 0.0, map[string]bool{}, 0
+	for _, tok := range tokens {
+		types[tok] = true
+		n++
+		if float64(len(types))/float64(n) <= mtldThreshold {
+			factors++
+			types, n = map[string]bool{}, 0
+		}
+	}
+	if n > 0 {
+		// What's left is part of a factor: as much as its ratio has fallen
+		// toward the threshold
+		factors += (1 - float64(len(types))/float64(n)) / (1 - mtldThreshold)
+	}
+	if factors <= 0 {
+		return float64(len(tokens))
+	}
+	return float64(len(tokens)) / factors
+}
+
+// hddSample is the number of words HD-D draws.
+const hddSample = 42
+
+// HDD is the expected type-token ratio of 42 words drawn at random from the
+// text, per the hypergeometric distribution. Texts of fewer than 42 words
+// score their type-token ratio.
+// McCarthy and Jarvis, "vocd: A theoretical and empirical evaluation" (2007)
+func HDD(tokens []string) float64 {
+	n, sample := len(tokens), hddSample
+	if n <= 0 {
+		return 0
+	}
+	if n < sample {
+		sample = n
+	}
+	d := 0.0
+	for _, count := range frequencies(tokens) {
+		// The chance the sample includes the type, at least once
+		d += 1 - hypergeometricZero(n, count, sample)
+	}
+	return d / float64(sample)
+}
+
+// hypergeometricZero is the probability that a sample of n from a
+// population of size, with k successes, has none of them.
+func hypergeometricZero(size, k, n int) float64 {
+	if size-k < n {
+		return 0
+	}
+	return math.Exp(lnChoose(size-k, n) - lnChoose(size, n))
+}
+
+func lnChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
+
+// YulesK is 10⁴(Σm²Vₘ - N)/N², where Vₘ is the number of types that occur m
+// times, and N the number of words. It measures repetition, so it's lower
+// for more diverse texts, and it doesn't depend on length.
+// Yule, "The Statistical Study of Literary Vocabulary" (1944)
+func YulesK(tokens []string) float64 {
+	n := float64(len(tokens))
+	if n <= 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, count := range frequencies(tokens) {
+		sum += float64(count * count)
+	}
+	return 1e4 * (sum - n) / (n * n)
+}
+
+// HapaxRatio is the share of types that occur only once: hapax legomena.
+func HapaxRatio(tokens []string) float64 {
+	freq := frequencies(tokens)
+	if len(freq) <= 0 {
+		return 0
+	}
+	hapaxes := 0
+	for _, count := range freq {
+		if count == 1 {
+			hapaxes++
+		}
+	}
+	return float64(hapaxes) / float64(len(freq))
+}
+
+// diversityFunc adapts a lexical diversity measure to a ScoringFunction.
+func diversityFunc(f func(tokens []string) float64) ScoringFunction {
+	return func(r Review) float64 { return f(tokenize(r.Body)) }
+}
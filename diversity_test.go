@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestDiversity(t *testing.T) {
+	for _, tuple := range []struct {
+		text                     string
+		ttr, mtld, hdd, k, hapax float64
+	}{
+		{"", 0, 0, 0, 0, 0},
+		{"lush", 1, 1, 1, 0, 1},
+		{"a a b c", 0.75, (4 + 4/(0.25/0.28)) / 2, 0.75, 1250, 2.0 / 3},
+		{"a a a a a a a a a a", 0.1, 2, 0.1, 9000, 0},
+	} {
+		tokens := strings.Fields(tuple.text)
+		for _, metric := range []struct {
+			name     string
+			f        func([]string) float64
+			expected float64
+		}{
+			{"TTR", TypeTokenRatio, tuple.ttr},
+			{"MTLD", MTLD, tuple.mtld},
+			{"HD-D", HDD, tuple.hdd},
+			{"Yule's K", YulesK, tuple.k},
+			{"hapax ratio", HapaxRatio, tuple.hapax},
+		} {
+			if got := metric.f(tokens); math.Abs(got-metric.expected) > 1e-9 {
+				t.Errorf("%q: %s: got %v, expected %v", tuple.text, metric.name, got, metric.expected)
+			}
+		}
+	}
+}
+
+func TestHDD(t *testing.T) {
+	// 84 words, 2 types of 42 each: any sample of 42 almost surely has both
+	tokens := strings.Fields(strings.Repeat("a b ", 42))
+	if got, expected := HDD(tokens), 2.0/42; math.Abs(got-expected) > 1e-9 {
+		t.Errorf("got %v, expected %v", got, expected)
+	}
+	// All distinct: every sample of 42 is too
+	tokens = []string{}
+	for i := 0; i < 84; i++ {
+		tokens = append(tokens, fmt.Sprint(i))
+	}
+	if got := HDD(tokens); math.Abs(got-1) > 1e-9 {
+		t.Errorf("got %v, expected 1", got)
+	}
+}
+
+// zipf returns n words drawn from a Zipfian vocabulary, like natural text.
+func zipf(seed int64, n int) []string {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), 1.2, 2, 5000)
+	tokens := make([]string, n)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("w%d", z.Uint64())
+	}
+	return tokens
+}
+
+func TestDiversityLength(t *testing.T) {
+	long := zipf(1, 4000)
+	short := long[:1000]
+	if s, l := TypeTokenRatio(short), TypeTokenRatio(long); l > 0.8*s {
+		t.Errorf("TTR: short %.3f, long %.3f; expected it to fall with length", s, l)
+	}
+	for _, metric := range []struct {
+		name string
+		f    func([]string) float64
+	}{
+		{"MTLD", MTLD},
+		{"HD-D", HDD},
+		{"Yule's K", YulesK},
+	} {
+		s, l := metric.f(short), metric.f(long)
+		if math.Abs(s-l)/s > 0.2 {
+			t.Errorf("%s: short %.3f, long %.3f; expected within 20%%", metric.name, s, l)
+		}
+	}
+}
+
+func TestDiversityIndexes(t *testing.T) {
+	r := Review{Body: "<p>Lush, <em>lush</em> and ethereal.</p>"}
+	if got := diversityFunc(TypeTokenRatio)(r); got != 0.75 {
+		t.Errorf("got %v, expected 0.75", got)
+	}
+	for _, name := range []string{"Type-token ratio", "MTLD", "HD-D", "Yule's K", "Hapax ratio"} {
+		if _, ok := LookupIndex(name); !ok {
+			t.Errorf("%q isn't registered", name)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/peterbourgon/exp-html"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Evidence is a span of a review's body that contributed to a score.
+type Evidence struct {
+	Kind   string  `json:"kind"`  // e.g. "term", "invented", "sentence"
+	Start  int     `json:"start"` // byte offsets into the body
+	End    int     `json:"end"`
+	Text   string  `json:"text"` // the word or sentence, normalized
+	Weight float64 `json:"weight"`
+}
+
+// An ExplainingFunction scores a review, and gives the evidence for its
+// score.
+type ExplainingFunction func(Review) (float64, []Evidence)
+
+// ScoringFunction drops the evidence.
+func (e ExplainingFunction) ScoringFunction() ScoringFunction {
+	return func(r Review) float64 {
+		score, _ := e(r)
+		return score
+	}
+}
+
+// Unexplained adapts a ScoringFunction that gives no evidence.
+func Unexplained(f ScoringFunction) ExplainingFunction {
+	return func(r Review) (float64, []Evidence) { return f(r), []Evidence{} }
+}
+
+type ExplainerMap map[string]ExplainingFunction
+
+// IndexMap returns the ScoringFunctions of every ExplainingFunction.
+func (m ExplainerMap) IndexMap() IndexMap {
+	indexes := IndexMap{}
+	for name, e := range m {
+		indexes[name] = e.ScoringFunction()
+	}
+	return indexes
+}
+
+//
+//
+//
+
+// An Explanation breaks down every score of a review.
+type Explanation struct {
+	Review     Review                 `json:"-"`
+	Indexes    []IndexExplanation     `json:"indexes"`
+	Composites []CompositeExplanation `json:"composites"`
+}
+
+// An IndexExplanation gives the stored score for an index, and the evidence
+// for it from rescoring the review, which gives the Rescored score.
+type IndexExplanation struct {
+	Name     string     `json:"name"`
+	Score    float64    `json:"score"`
+	Rescored float64    `json:"rescored"`
+	Evidence []Evidence `json:"evidence"`
+}
+
+// Differs reports whether the rescore differs from the stored score, as
+// displayed. The evidence then doesn't quite account for the score.
+func (ie IndexExplanation) Differs() bool {
+	return FormatScore(ie.Name, ie.Score) != FormatScore(ie.Name, ie.Rescored)
+}
+
+type CompositeExplanation struct {
+	Name       string      `json:"name"`
+	Score      float64     `json:"score"`
+	Components []Component `json:"components"`
+}
+
+// A Component is one index's contribution to a composite.
+type Component struct {
+	Index        string  `json:"index"`
+	Score        float64 `json:"score"`
+	Normalized   float64 `json:"normalized"`
+	Weight       int     `json:"weight"`
+	Contribution float64 `json:"contribution"`
+}
+
+// An Explainer explains the scores of reviews in a Store. Composites are
+// normalized against the stored scores of every review, which are read
+// once, on first use.
+type Explainer struct {
+	Store      Store
+	Indexes    ExplainerMap
+	Composites []Composite
+
+	once     sync.Once
+	allStats AllStatisticalData
+	err      error
+}
+
+func (e *Explainer) stats() (AllStatisticalData, error) {
+	e.once.Do(func() {
+		reviews, err := e.Store.SelectAllReviews()
+		if err != nil {
+			e.err = err
+			return
+		}
+		e.allStats = AllStatisticalData{}
+		for indexName, _ := range e.Indexes {
+			e.allStats[indexName] = Gather(reviews, indexName)
+		}
+	})
+	return e.allStats, e.err
+}
+
+// Explain explains the stored scores of the review with the given ID.
+// Composites are broken down from the stored index scores, as they were
+// computed; each index is rescored only for its evidence. A review with no
+// stored score for an index takes its rescore.
+func (e *Explainer) Explain(id int) (Explanation, error) {
+	reviews, err := e.Store.SelectReviews([]int{id})
+	if err != nil {
+		return Explanation{}, err
+	}
+	review, ok := reviews[id]
+	if !ok {
+		return Explanation{}, fmt.Errorf("no review %d", id)
+	}
+	allStats, err := e.stats()
+	if err != nil {
+		return Explanation{}, err
+	}
+
+	x := Explanation{Review: review}
+	names := []string{}
+	for name, _ := range e.Indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rescored, evidence := e.Indexes[name](review)
+		score, ok := review.Scores[name]
+		if !ok {
+			score = rescored
+			review.Scores[name] = score
+		}
+		x.Indexes = append(x.Indexes, IndexExplanation{name, score, rescored, evidence})
+	}
+	for _, c := range e.Composites {
+		ce := CompositeExplanation{Name: c.Name}
+		indexNames := []string{}
+		for indexName, _ := range c.Weights {
+			indexNames = append(indexNames, indexName)
+		}
+		sort.Strings(indexNames)
+		for _, indexName := range indexNames {
+			score := review.Scores[indexName]
+			normalized := c.normalize(indexName, score, allStats[indexName])
+			component := Component{
+				Index:        indexName,
+				Score:        score,
+				Normalized:   normalized,
+				Weight:       c.Weights[indexName],
+				Contribution: float64(c.Weights[indexName]) * normalized,
+			}
+			ce.Score += component.Contribution
+			ce.Components = append(ce.Components, component)
+		}
+		x.Composites = append(x.Composites, ce)
+	}
+	return x, nil
+}
+
+//
+//
+//
+
+// WriteExplanation writes x as plain text.
+func WriteExplanation(w io.Writer, x Explanation) {
+	fmt.Fprintf(w, "Review %d: %s (%s)\n", x.Review.ID, x.Review.Permalink, x.Review.Author)
+	for _, ie := range x.Indexes {
+		if ie.Differs() {
+			fmt.Fprintf(w, "\n%s: %s (rescored %s)\n", ie.Name, FormatScore(ie.Name, ie.Score), FormatScore(ie.Name, ie.Rescored))
+		} else {
+			fmt.Fprintf(w, "\n%s: %s\n", ie.Name, FormatScore(ie.Name, ie.Score))
+		}
+		for _, ev := range ie.Evidence {
+			fmt.Fprintf(w, "    %-8s %6d-%-6d %6.1f  %s\n", ev.Kind, ev.Start, ev.End, ev.Weight, ev.Text)
+		}
+	}
+	for _, ce := range x.Composites {
+		fmt.Fprintf(w, "\n%s: %s\n", ce.Name, FormatScore(ce.Name, ce.Score))
+		for _, c := range ce.Components {
+			fmt.Fprintf(
+				w,
+				"    %-24s %10s -> %6.2f x %3d = %7.2f\n",
+				c.Index,
+				FormatScore(c.Index, c.Score),
+				c.Normalized,
+				c.Weight,
+				c.Contribution,
+			)
+		}
+	}
+}
+
+// Highlight returns the review's body, sanitized, with its evidence marked
+// up: words as <mark class="kind">, and the ends of sentences with a
+// marker. Where words overlap, the first wins.
+func (x Explanation) Highlight() string {
+	marks := []mark{}
+	for _, ie := range x.Indexes {
+		for _, ev := range ie.Evidence {
+			marks = append(marks, mark{ev, ie.Name})
+		}
+	}
+	sort.Stable(marksByPosition(marks))
+
+	inserts, at := []insert{}, 0
+	seen := map[int]bool{} // sentence ends already marked
+	for _, m := range marks {
+		if m.Kind == "sentence" {
+			if m.End < at || seen[m.End] {
+				continue
+			}
+			seen[m.End] = true
+			inserts = append(inserts, insert{m.End, `<span class="sentence-end" title="` +
+				html.EscapeString(fmt.Sprintf("%.0f-word sentence", m.Weight)) + `">|</span>`})
+			at = m.End
+			continue
+		}
+		if m.Start < at {
+			continue // overlaps
+		}
+		inserts = append(
+			inserts,
+			insert{m.Start, fmt.Sprintf(
+				`<mark class="%s" title="%s">`,
+				html.EscapeString(m.Kind),
+				html.EscapeString(fmt.Sprintf("%s %+g", m.indexName, m.Weight)),
+			)},
+			insert{m.End, "</mark>"},
+		)
+		at = m.End
+	}
+	return sanitize(x.Review.Body, inserts)
+}
+
+// An insert is markup to put into a body at a byte offset.
+type insert struct {
+	at   int
+	html string
+}
+
+// allowedTags are the tags a sanitized body keeps, without attributes.
+var allowedTags = map[string]bool{
+	"p": true, "br": true, "em": true, "i": true, "strong": true, "b": true,
+	"blockquote": true, "ul": true, "ol": true, "li": true,
+}
+
+// sanitize returns body with only allowedTags, and with its text escaped,
+// so that markup scraped with a review can't run in the page. Scripts and
+// styles are dropped whole. The inserts, in order, are put in as they are.
+func sanitize(body string, inserts []insert) string {
+	z := html.NewTokenizer(strings.NewReader(body))
+	out, offset, k, skipping := []string{}, 0, 0, ""
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		start := offset
+		offset += len(raw)
+		for ; k < len(inserts) && inserts[k].at <= start; k++ {
+			out = append(out, inserts[k].html)
+		}
+		switch tt {
+		case html.TextToken:
+			if skipping != "" {
+				continue
+			}
+			pos := start
+			for ; k < len(inserts) && inserts[k].at < offset; k++ {
+				out = append(out, escapeText(raw[pos-start:inserts[k].at-start]), inserts[k].html)
+				pos = inserts[k].at
+			}
+			out = append(out, escapeText(raw[pos-start:]))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case tag == "script" || tag == "style":
+				if tt == html.StartTagToken {
+					skipping = tag
+				}
+			case skipping == "" && allowedTags[tag]:
+				out = append(out, "<"+tag+">")
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch tag := string(name); {
+			case tag == skipping:
+				skipping = ""
+			case skipping == "" && allowedTags[tag] && tag != "br":
+				out = append(out, "</"+tag+">")
+			}
+		}
+	}
+	for ; k < len(inserts); k++ {
+		out = append(out, inserts[k].html)
+	}
+	return strings.Join(out, "")
+}
+
+// escapeText re-escapes raw HTML text, whatever references it used.
+func escapeText(raw string) string {
+	return html.EscapeString(html.UnescapeString(raw))
+}
+
+type mark struct {
+	Evidence
+	indexName string
+}
+
+// position is where a mark goes: sentences at their ends.
+func (m mark) position() int {
+	if m.Kind == "sentence" {
+		return m.End
+	}
+	return m.Start
+}
+
+type marksByPosition []mark
+
+func (a marksByPosition) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a marksByPosition) Len() int           { return len(a) }
+func (a marksByPosition) Less(i, j int) bool { return a[i].position() < a[j].position() }
+
+//
+//
+//
+
+// ServeHTTP renders /explain/{id} as a page with the review's evidence
+// highlighted, or as JSON with ?format=json.
+func (e *Explainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/explain/"))
+	if err != nil {
+		http.Error(w, "bad review ID", http.StatusBadRequest)
+		return
+	}
+	x, err := e.Explain(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(x)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := explainTemplate.Execute(w, map[string]interface{}{
+		"Explanation": x,
+		"Body":        template.HTML(x.Highlight()),
+		"Format":      FormatScore,
+	}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var explainTemplate = template.Must(template.New("explain").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8">
+    <title>Pitchdex: review {{.Explanation.Review.ID}}</title>
+    <link href="/css/bootstrap.min.css" rel="stylesheet">
+    <style>
+      mark.term { background: #fcf8e3; }
+      mark.invented { background: #f2dede; }
+      mark.phrase { background: #dff0d8; }
+      .sentence-end { color: #3a87ad; font-weight: bold; }
+    </style>
+  </head>
+  <body>
+    <div class="container">
+      {{with .Explanation}}
+      <h1>{{.Review.Artist}}: {{.Review.Album}}</h1>
+      <p>{{.Review.Author}} &middot; {{.Review.Permalink}}</p>
+      {{end}}
+      <table class="table table-bordered">
+        {{range .Explanation.Composites}}
+        <tr><th colspan="5">{{.Name}}: {{call $.Format .Name .Score}}</th></tr>
+        {{range .Components}}
+        <tr>
+          <td>{{.Index}}</td>
+          <td>{{call $.Format .Index .Score}}</td>
+          <td>{{printf "%.2f" .Normalized}}</td>
+          <td>&times; {{.Weight}}</td>
+          <td>{{printf "%.2f" .Contribution}}</td>
+        </tr>
+        {{end}}
+        {{end}}
+        {{range .Explanation.Indexes}}
+        <tr><td>{{.Name}}</td><td colspan="4">{{call $.Format .Name .Score}}{{if .Differs}} (rescored {{call $.Format .Name .Rescored}}){{end}}</td></tr>
+        {{end}}
+      </table>
+      <div class="review">{{.Body}}</div>
+    </div>
+  </body>
+</html>
+`))
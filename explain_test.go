@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+const explainBody = `<p>A <em>lush</em>, ethereal record&hellip; Mostly.</p><p>Flibbertigibbet x.</p>`
+
+func TestEvidenceOffsets(t *testing.T) {
+	r := Review{Body: explainBody}
+	score, evidence := ExplainPitchformulaity(r)
+	if score != 18 || len(evidence) != 2 {
+		t.Fatalf("got %.1f, %v; expected 18 from lush and ethereal", score, evidence)
+	}
+	for _, ev := range evidence {
+		if got := r.Body[ev.Start:ev.End]; got != ev.Text {
+			t.Errorf("%s: offsets [%d:%d] give %q", ev.Text, ev.Start, ev.End, got)
+		}
+	}
+
+	filename := writeConfig(t, "a\nrecord\nmostly\nx\n")
+	defer os.Remove(filename)
+	dict, err := NewDict(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	score, evidence = ExplainInventedWordsFunc(dict)(r)
+	got := []string{}
+	for _, ev := range evidence {
+		got = append(got, r.Body[ev.Start:ev.End])
+	}
+	if score != 3 || !equal(got, []string{"lush", "ethereal", "Flibbertigibbet"}) {
+		t.Errorf("got %.1f, %q", score, got)
+	}
+
+	_, evidence = ExplainMeanSentenceLength(r)
+	got = []string{}
+	for _, ev := range evidence {
+		got = append(got, stripHTML(r.Body[ev.Start:ev.End]))
+	}
+	if !equal(got, []string{"A lush, ethereal record…", "Mostly.", "Flibbertigibbet x."}) {
+		t.Errorf("got sentences %q", got)
+	}
+}
+
+func explainer(t *testing.T) *Explainer {
+	s := NewMemoryStore()
+	reviews := Reviews{}
+	for i, body := range []string{explainBody, "<p>Warm. Warm warm.</p>", "<p>Plain words here.</p>"} {
+		reviews[i+1] = Review{ID: i + 1, Author: "A", Body: body, Scores: map[string]float64{}}
+	}
+	indexes := ExplainerMap{
+		"Pitchformulaity": ExplainPitchformulaity,
+		"Sentence length": ExplainMeanSentenceLength,
+		"Word count":      Unexplained(WordCount),
+	}
+	for id, review := range reviews {
+		for name, e := range indexes {
+			reviews[id].Scores[name], _ = e(review)
+		}
+	}
+	if err := s.InsertReviews(reviews); err != nil {
+		t.Fatalf("%s", err)
+	}
+	return &Explainer{
+		Store:   s,
+		Indexes: indexes,
+		Composites: []Composite{Composite{
+			Name:           BullshitScore,
+			Weights:        map[string]int{"Pitchformulaity": 10, "Word count": 2},
+			Normalizations: map[string]Normalization{"Word count": ZScoreNormalization{}},
+		}},
+	}
+}
+
+func TestExplainComposite(t *testing.T) {
+	e := explainer(t)
+	x, err := e.Explain(1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(x.Indexes) != 3 || len(x.Composites) != 1 {
+		t.Fatalf("got %+v", x)
+	}
+	reviews, _ := e.Store.SelectAllReviews()
+	allStats := AllStatisticalData{}
+	for name, _ := range e.Indexes {
+		allStats[name] = Gather(reviews, name)
+	}
+	expected := e.Composites[0].Score(reviews[1], allStats)
+	ce, total := x.Composites[0], 0.0
+	for _, c := range ce.Components {
+		total += c.Contribution
+	}
+	if math.Abs(ce.Score-expected) > 1e-9 || math.Abs(total-expected) > 1e-9 {
+		t.Errorf("got composite %.4f from components totalling %.4f, expected %.4f", ce.Score, total, expected)
+	}
+	if _, err := e.Explain(99); err == nil {
+		t.Errorf("explained a nonexistent review")
+	}
+}
+
+// Composites are explained from the stored scores, even when a rescore
+// differs.
+func TestExplainStoredScores(t *testing.T) {
+	e := explainer(t)
+	if err := e.Store.InsertReviewScores(map[int]map[string]float64{1: {"Pitchformulaity": 5}}, true); err != nil {
+		t.Fatalf("%s", err)
+	}
+	x, err := e.Explain(1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	ie := x.Indexes[0]
+	if ie.Name != "Pitchformulaity" || ie.Score != 5 || ie.Rescored != 18 || !ie.Differs() {
+		t.Errorf("got %+v, expected stored 5, rescored 18", ie)
+	}
+	if x.Indexes[1].Differs() {
+		t.Errorf("%s differs: %+v", x.Indexes[1].Name, x.Indexes[1])
+	}
+	for _, c := range x.Composites[0].Components {
+		if c.Index == "Pitchformulaity" && c.Score != 5 {
+			t.Errorf("composite used %.1f, expected the stored 5", c.Score)
+		}
+	}
+	var buf bytes.Buffer
+	WriteExplanation(&buf, x)
+	if !strings.Contains(buf.String(), "Pitchformulaity: 5.0 (rescored 18.0)") {
+		t.Errorf("difference not flagged in %s", buf.String())
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	x, err := explainer(t).Explain(1)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	got := x.Highlight()
+	for _, expected := range []string{
+		`<em><mark class="term" title="Pitchformulaity +9">lush</mark></em>`,
+		`<mark class="term" title="Pitchformulaity +9">ethereal</mark>`,
+		`record…<span class="sentence-end" title="4-word sentence">|</span> Mostly.`,
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("expected %s in %s", expected, got)
+		}
+	}
+	if stripped := stripHTML(strings.Replace(got, "|", "", -1)); stripped != stripHTML(explainBody) {
+		t.Errorf("highlighting changed the text: %s", stripped)
+	}
+}
+
+func TestHighlightSanitized(t *testing.T) {
+	body := `<p onclick="x()">Lush <img src=x onerror="alert(1)"><script>alert("<p>")</script>` +
+		`&lt;b&gt; <a href="javascript:x()">ethereal</a><style>p {}</style></p>`
+	x := Explanation{
+		Review: Review{Body: body},
+		Indexes: []IndexExplanation{{"Pitchformulaity", 18, 18, []Evidence{
+			{"term", 17, 21, "lush", 9},
+			{"term", strings.Index(body, "ethereal"), strings.Index(body, "ethereal") + 8, "ethereal", 9},
+		}}},
+	}
+	got := x.Highlight()
+	for _, unexpected := range []string{"<img", "<script", "alert", "onclick", "<a ", "javascript", "<style", "<b>"} {
+		if strings.Contains(got, unexpected) {
+			t.Errorf("%s survived in %s", unexpected, got)
+		}
+	}
+	expected := `<p><mark class="term" title="Pitchformulaity +9">Lush</mark> &lt;b&gt; ` +
+		`<mark class="term" title="Pitchformulaity +9">ethereal</mark></p>`
+	if got != expected {
+		t.Errorf("got %s, expected %s", got, expected)
+	}
+}
+
+func TestExplainHTTP(t *testing.T) {
+	e := explainer(t)
+	for path, expected := range map[string]int{
+		"/explain/1":             200,
+		"/explain/1?format=json": 200,
+		"/explain/99":            404,
+		"/explain/x":             400,
+	} {
+		w := httptest.NewRecorder()
+		e.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != expected {
+			t.Errorf("%s: got %d, expected %d", path, w.Code, expected)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/explain/2?format=json", nil))
+	var x Explanation
+	if err := json.Unmarshal(w.Body.Bytes(), &x); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got := fmt.Sprint(len(x.Indexes[0].Evidence)); x.Indexes[0].Name != "Pitchformulaity" || got != "3" {
+		t.Errorf("got %+v, expected 3 pieces of Pitchformulaity evidence", x.Indexes[0])
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// importBatchSize is the number of records written, and checkpointed,
+// together.
+const importBatchSize = 1000
+
+// openImport opens filename for reading, transparently gunzipping it.
+func openImport(filename string) (io.ReadCloser, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(2)
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return readCloser{br, f}, nil
+	}
+	z, err := gzip.NewReader(br)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return readCloser{z, f}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// recordReader streams raw records from either a JSON array, or
+// newline-delimited JSON (one record per line).
+type recordReader struct {
+	dec   *json.Decoder // JSON array
+	lines *bufio.Reader // NDJSON
+	n     int
+}
+
+func newRecordReader(r io.Reader) (*recordReader, error) {
+	br := bufio.NewReader(r)
+	for {
+		c, err := br.ReadByte()
+		if err == io.EOF {
+			return &recordReader{lines: br}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == ' ' || c == '\t' || c == '\r' || c == '\n' {
+			continue
+		}
+		br.UnreadByte()
+		if c != '[' {
+			return &recordReader{lines: br}, nil
+		}
+		dec := json.NewDecoder(br)
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return &recordReader{dec: dec}, nil
+	}
+}
+
+// Next returns the next raw record, or io.EOF. Any other error is fatal. A
+// malformed NDJSON line is returned as-is, to be rejected by the caller;
+// a malformed array can't be resynchronized, and is fatal.
+func (rr *recordReader) Next() ([]byte, error) {
+	if rr.dec != nil {
+		if !rr.dec.More() {
+			return nil, io.EOF
+		}
+		var raw json.RawMessage
+		if err := rr.dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("record %d: %s", rr.n+1, err)
+		}
+		rr.n++
+		return raw, nil
+	}
+	for {
+		line, err := rr.lines.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			rr.n++
+			return bytes.TrimSpace(line), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Position returns the 1-based position of the last record returned.
+func (rr *recordReader) Position() int {
+	return rr.n
+}
+
+//
+//
+//
+
+// An ImportError describes a record that was skipped.
+type ImportError struct {
+	Record int             `json:"record"` // 1-based position in the input
+	Key    string          `json:"key,omitempty"`
+	Reason string          `json:"reason"`
+	Raw    json.RawMessage `json:"raw"`
+}
+
+func (e ImportError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("record %d: %s", e.Record, e.Reason)
+	}
+	return fmt.Sprintf("record %d (%s): %s", e.Record, e.Key, e.Reason)
+}
+
+type ImportReport struct {
+	Resumed  int // records skipped per the checkpoint
+	Imported int
+	Existing int // records already in the store, and not reimported
+	Errors   []ImportError
+}
+
+// An Importer streams reviews from a JSON file into a Store, in batches. Bad
+// records, including repeats of a review ID earlier in the file, are
+// reported, and optionally quarantined, rather than aborting the import.
+// After every batch, the position is checkpointed, so an interrupted import
+// resumes where it left off; the records before the checkpoint still count
+// as earlier in the file.
+type Importer struct {
+	Store      Store
+	Reimport   bool
+	Quarantine io.Writer // bad records as NDJSON ImportErrors; may be nil
+	Checkpoint string    // checkpoint filename; empty disables resumption
+}
+
+type importCheckpoint struct {
+	File    string `json:"file"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mod_time"`
+	Record  int    `json:"record"` // records done
+}
+
+func (imp Importer) Import(filename string) (ImportReport, error) {
+	report := ImportReport{}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return report, err
+	}
+	checkpoint := importCheckpoint{
+		File:    filename,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime().Unix(),
+	}
+	resumeAt := imp.resumePosition(checkpoint)
+
+	f, err := openImport(filename)
+	if err != nil {
+		return report, err
+	}
+	defer f.Close()
+	rr, err := newRecordReader(f)
+	if err != nil {
+		return report, err
+	}
+
+	batch, seen := Reviews{}, map[int]int{} // review ID -> first record
+	for {
+		raw, err := rr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		if rr.Position() <= resumeAt {
+			report.Resumed++
+			var jsonReview JSONReview
+			if json.Unmarshal(raw, &jsonReview) == nil {
+				if review, err := jsonReview.Review(); err == nil {
+					if _, ok := seen[review.ID]; !ok {
+						seen[review.ID] = rr.Position()
+					}
+				}
+			}
+			continue
+		}
+		var jsonReview JSONReview
+		if err := json.Unmarshal(raw, &jsonReview); err != nil {
+			imp.reject(&report, ImportError{rr.Position(), "", err.Error(), raw})
+		} else if review, err := jsonReview.Review(); err != nil {
+			imp.reject(&report, ImportError{rr.Position(), jsonReview.Permalink, err.Error(), raw})
+		} else if first, ok := seen[review.ID]; ok {
+			imp.reject(&report, ImportError{
+				rr.Position(),
+				jsonReview.Permalink,
+				fmt.Sprintf("duplicate review ID %d (first at record %d)", review.ID, first),
+				raw,
+			})
+		} else {
+			seen[review.ID] = rr.Position()
+			batch[review.ID] = review
+		}
+		if rr.Position()%importBatchSize == 0 {
+			if err := imp.flush(batch, &report); err != nil {
+				return report, err
+			}
+			batch = Reviews{}
+			checkpoint.Record = rr.Position()
+			if err := imp.saveCheckpoint(checkpoint); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := imp.flush(batch, &report); err != nil {
+		return report, err
+	}
+	if imp.Checkpoint != "" {
+		os.Remove(imp.Checkpoint) // done
+	}
+	return report, nil
+}
+
+func (imp Importer) reject(report *ImportReport, e ImportError) {
+	if e.Raw == nil || !json.Valid(e.Raw) {
+		raw, _ := json.Marshal(string(e.Raw))
+		e.Raw = raw
+	}
+	report.Errors = append(report.Errors, e)
+	if imp.Quarantine != nil {
+		buf, _ := json.Marshal(e)
+		imp.Quarantine.Write(append(buf, '\n'))
+	}
+}
+
+// flush writes the batch to the Store, skipping existing reviews unless
+// reimporting.
+func (imp Importer) flush(batch Reviews, report *ImportReport) error {
+	if len(batch) <= 0 {
+		return nil
+	}
+	if !imp.Reimport {
+		ids := make([]int, 0, len(batch))
+		for id, _ := range batch {
+			ids = append(ids, id)
+		}
+		existing, err := imp.Store.SelectBodys(ids)
+		if err != nil {
+			return err
+		}
+		for id, _ := range existing {
+			delete(batch, id)
+			report.Existing++
+		}
+	}
+	if err := imp.Store.InsertReviews(batch); err != nil {
+		return err
+	}
+	report.Imported += len(batch)
+	return nil
+}
+
+// resumePosition returns the number of records already imported, per a
+// checkpoint matching the given one.
+func (imp Importer) resumePosition(current importCheckpoint) int {
+	if imp.Checkpoint == "" {
+		return 0
+	}
+	f, err := os.Open(imp.Checkpoint)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	var saved importCheckpoint
+	if err := json.NewDecoder(f).Decode(&saved); err != nil {
+		return 0
+	}
+	if saved.File != current.File || saved.Size != current.Size || saved.ModTime != current.ModTime {
+		return 0 // a different file
+	}
+	return saved.Record
+}
+
+func (imp Importer) saveCheckpoint(checkpoint importCheckpoint) error {
+	if imp.Checkpoint == "" {
+		return nil
+	}
+	buf, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp := imp.Checkpoint + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, imp.Checkpoint)
+}
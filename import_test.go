@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func writeImport(t *testing.T, contents string, gzipped bool) string {
+	f, err := ioutil.TempFile("", "pitchdex-import")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	defer f.Close()
+	if gzipped {
+		z := gzip.NewWriter(f)
+		z.Write([]byte(contents))
+		z.Close()
+	} else {
+		f.WriteString(contents)
+	}
+	return f.Name()
+}
+
+const (
+	goodRecord1 = `{"reviewers": "A", "editorial": "One.", "key": "1-one"}`
+	goodRecord2 = `{"reviewers": "B", "editorial": "Two.", "key": "2-two"}`
+	noAuthor    = `{"reviewers": "", "editorial": "Three.", "key": "3-three"}`
+	badKey      = `{"reviewers": "C", "editorial": "Four.", "key": "four"}`
+	badType     = `{"reviewers": "D", "editorial": "Five.", "key": "5-five", "year": "1999"}`
+)
+
+func TestImportFormats(t *testing.T) {
+	array := "[" + strings.Join([]string{goodRecord1, noAuthor, goodRecord2, badKey, badType}, ",\n") + "]"
+	ndjson := strings.Join([]string{goodRecord1, noAuthor, goodRecord2, badKey, badType, `{"broken`}, "\n")
+	for name, input := range map[string]struct {
+		contents string
+		gzipped  bool
+		errors   int
+	}{
+		"array":          {array, false, 3},
+		"gzipped array":  {array, true, 3},
+		"ndjson":         {ndjson, false, 4},
+		"gzipped ndjson": {ndjson, true, 4},
+	} {
+		filename := writeImport(t, input.contents, input.gzipped)
+		defer os.Remove(filename)
+		store, quarantine := NewMemoryStore(), &bytes.Buffer{}
+		report, err := Importer{Store: store, Quarantine: quarantine}.Import(filename)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if report.Imported != 2 {
+			t.Errorf("%s: imported %d, expected 2", name, report.Imported)
+		}
+		if len(report.Errors) != input.errors {
+			t.Errorf("%s: got %d errors, expected %d: %v", name, len(report.Errors), input.errors, report.Errors)
+		}
+		if n := strings.Count(quarantine.String(), "\n"); n != input.errors {
+			t.Errorf("%s: quarantined %d, expected %d", name, n, input.errors)
+		}
+		if len(report.Errors) > 0 && report.Errors[0].Record != 2 {
+			t.Errorf("%s: first error at record %d, expected 2", name, report.Errors[0].Record)
+		}
+		bodys, _ := store.SelectBodys([]int{1, 2})
+		if bodys[1] != "One." || bodys[2] != "Two." {
+			t.Errorf("%s: got %v", name, bodys)
+		}
+	}
+}
+
+func TestImportDuplicateIDs(t *testing.T) {
+	again := `{"reviewers": "C", "editorial": "One again.", "key": "1-one-again"}`
+	filename := writeImport(t, strings.Join([]string{goodRecord1, goodRecord2, again}, "\n"), false)
+	defer os.Remove(filename)
+	store := NewMemoryStore()
+	report, err := Importer{Store: store}.Import(filename)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if report.Imported != 2 || len(report.Errors) != 1 {
+		t.Fatalf("imported %d with errors %v, expected 2 and one error", report.Imported, report.Errors)
+	}
+	if e := report.Errors[0]; e.Record != 3 || e.Key != "1-one-again" || !strings.Contains(e.Reason, "record 1") {
+		t.Errorf("got %v, expected the duplicate at record 3", e)
+	}
+	bodys, _ := store.SelectBodys([]int{1})
+	if bodys[1] != "One." {
+		t.Errorf("got %q, expected the first record to win", bodys[1])
+	}
+}
+
+// A repeat of a review ID imported before the checkpoint is still a
+// duplicate once the import resumes.
+func TestImportResumeDuplicateIDs(t *testing.T) {
+	lines := []string{}
+	for i := 1; i <= importBatchSize; i++ {
+		lines = append(lines, fmt.Sprintf(`{"reviewers": "A", "editorial": "Body.", "key": "%d-x"}`, i))
+	}
+	lines = append(
+		lines,
+		`{"reviewers": "B", "editorial": "Again.", "key": "1-again"}`,
+		fmt.Sprintf(`{"reviewers": "B", "editorial": "New.", "key": "%d-x"}`, importBatchSize+1),
+	)
+	filename := writeImport(t, strings.Join(lines, "\n"), false)
+	defer os.Remove(filename)
+	checkpoint := filename + ".checkpoint"
+	defer os.Remove(checkpoint)
+
+	memory := NewMemoryStore()
+	importer := Importer{Store: &failingStore{memory, 1}, Reimport: true, Checkpoint: checkpoint}
+	if _, err := importer.Import(filename); err == nil {
+		t.Fatalf("expected simulated failure, got none")
+	}
+	importer.Store = memory
+	report, err := importer.Import(filename)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Record != importBatchSize+1 {
+		t.Errorf("got errors %v, expected the duplicate at record %d", report.Errors, importBatchSize+1)
+	}
+	if bodys, _ := memory.SelectBodys([]int{1}); bodys[1] != "Body." {
+		t.Errorf("got %q, expected the first record to win", bodys[1])
+	}
+}
+
+// failingStore fails every InsertReviews after the first n.
+type failingStore struct {
+	*MemoryStore
+	n int
+}
+
+func (s *failingStore) InsertReviews(reviews Reviews) error {
+	if s.n <= 0 {
+		return fmt.Errorf("simulated failure")
+	}
+	s.n--
+	return s.MemoryStore.InsertReviews(reviews)
+}
+
+func TestImportResume(t *testing.T) {
+	lines := []string{}
+	for i := 1; i <= 2500; i++ {
+		lines = append(lines, fmt.Sprintf(`{"reviewers": "A", "editorial": "Body.", "key": "%d-x"}`, i))
+	}
+	filename := writeImport(t, strings.Join(lines, "\n"), false)
+	defer os.Remove(filename)
+	checkpoint := filename + ".checkpoint"
+	defer os.Remove(checkpoint)
+
+	// Interrupted after the first batch
+	memory := NewMemoryStore()
+	importer := Importer{Store: &failingStore{memory, 1}, Checkpoint: checkpoint}
+	if _, err := importer.Import(filename); err == nil {
+		t.Fatalf("expected simulated failure, got none")
+	}
+	reviews, _ := memory.SelectAllReviews()
+	if len(reviews) != importBatchSize {
+		t.Fatalf("got %d reviews after interruption, expected %d", len(reviews), importBatchSize)
+	}
+
+	// Resumed
+	importer.Store = memory
+	report, err := importer.Import(filename)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if report.Resumed != importBatchSize || report.Imported != 2500-importBatchSize {
+		t.Errorf("resumed %d, imported %d", report.Resumed, report.Imported)
+	}
+	reviews, _ = memory.SelectAllReviews()
+	if len(reviews) != 2500 {
+		t.Errorf("got %d reviews, expected 2500", len(reviews))
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Errorf("checkpoint not removed after a complete import")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDictKnown(t *testing.T) {
+	dict, err := NewDict("testdata/words.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, word := range GenreWords {
+		dict[word] = struct{}{}
+	}
+	for word, expected := range map[string]bool{
+		"record":          true,
+		"records":         true,  // plural
+		"record's":        true,  // possessive
+		"bands'":          true,  // plural possessive
+		"shimmered":       true,  // inflected
+		"shimmering":      true,  // inflected
+		"stopped":         true,  // doubled consonant
+		"happily":         true,  // y to i
+		"hurried":         true,  // y to i
+		"unhurried":       true,  // prefix and suffix
+		"unpredictably":   true,  // prefix and suffix
+		"rerecorded":      true,  // prefix and suffix
+		"post-rock":       true,  // compound; also a genre
+		"guitar-punk":     true,  // hyphenated compound
+		"songwall":        true,  // closed compound
+		"lo-fi":           true,  // genre
+		"synths":          true,  // genre, inflected
+		"shoegaze's":      true,  // genre, possessive
+		"they're":         true,  // contraction
+		"lushly":          true,  // derived
+		"guitarscapes":    false, // "scape" isn't in this dictionary
+		"flibbertigibbet": false,
+		"xyzzy":           false,
+		"-":               false,
+		"s":               false,
+	} {
+		if got := dict.Known(word); got != expected {
+			t.Errorf("%q: got %v, expected %v", word, got, expected)
+		}
+	}
+}
+
+func TestInventedWords(t *testing.T) {
+	dict, err := NewDict("testdata/words.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Review{Body: `<p>Flibbertigibbet is the best record of 2013, and its lo-fi songs shimmer.</p>` +
+		`<p>Mixing in Deerhunter, the band's 24-track albums are guitarscapes. Their <em>Xyzzy</em> was a flibbertigibbet.</p>`}
+	_, evidence := ExplainInventedWordsFunc(dict)(r)
+	got := []string{}
+	for _, ev := range evidence {
+		got = append(got, ev.Text)
+	}
+	// Sentence-initial "Flibbertigibbet" counts; "Deerhunter" and "Xyzzy" are
+	// names; "lo-fi" isn't a genre here, but "lo" and "fi" aren't words
+	expected := []string{"flibbertigibbet", "lo-fi", "guitarscapes", "flibbertigibbet"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %q, expected %q", got, expected)
+	}
+}
+
+func TestInventedWordsIndex(t *testing.T) {
+	idx, _ := LookupIndex("Words invented")
+	for _, p := range []Params{
+		Params{"dict": "testdata/nonexistent.txt"},
+		Params{"dict": "testdata/words.txt", "whitelist": "testdata/nonexistent.txt"},
+	} {
+		if _, err := idx.Build(p); err == nil || !strings.Contains(err.Error(), "nonexistent") {
+			t.Errorf("%v: got %v, expected an error naming the missing file", p, err)
+		}
+	}
+
+	empty := writeConfig(t, "\n")
+	defer os.Remove(empty)
+	if _, err := idx.Build(Params{"dict": empty}); err == nil {
+		t.Errorf("expected error for an empty dictionary")
+	}
+
+	whitelist := writeConfig(t, "Deerhunter\nguitarscape\n")
+	defer os.Remove(whitelist)
+	f, err := idx.Build(Params{"dict": "testdata/words.txt", "whitelist": whitelist})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Review{Body: "Guitarscapes of lo-fi post-rock, and xyzzy."}
+	if got := f(r); got != 1 {
+		t.Errorf("got %v, expected 1 (xyzzy)", got)
+	}
+}
@@ -109,12 +109,14 @@ $(document).ready(function() {
 			{ "mDataProp": "Author" },
 			{ "mDataProp": "Reviews" },
 			{ "mDataProp": "Overall Bullshit Score" },
+			{ "mDataProp": "Overall Bullshit Score (mean)" },
+			{ "mDataProp": "Overall Bullshit Score (95% CI)", "bSortable": false },
 			{ "mDataProp": "Pitchformulaity" },
-			{ "mDataProp": "Naïve sentence length" },
+			{ "mDataProp": "Sentence length" },
 			{ "mDataProp": "Word count" },
 			{ "mDataProp": "Words invented" }
 		],
-		"aaSorting": [[ 1, "desc" ]]
+		"aaSorting": [[ 2, "desc" ]]
 	});
 	$('#reviews').dataTable({
 		"bProcessing": true,
@@ -131,7 +133,7 @@ $(document).ready(function() {
 			{ "mDataProp": "Title" },
 			{ "mDataProp": "Author" },
 			{ "mDataProp": "Pitchformulaity" },
-			{ "mDataProp": "Naïve sentence length" },
+			{ "mDataProp": "Sentence length" },
 			{ "mDataProp": "Word count" },
 			{ "mDataProp": "Words invented" }
 		],
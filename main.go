@@ -5,96 +5,123 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
 )
 
 var (
-	dbFile      *string = flag.String("db", "pitchdex.db", "database file")
-	jsonFile    *string = flag.String("import", "", "JSON file to import (optional)")
-	reimport    *bool   = flag.Bool("reimport", false, "reimport existing reviews")
-	rescore     *bool   = flag.Bool("rescore", false, "rescore everything")
-	dictFile    *string = flag.String("dict", "/usr/share/dict/words", "dict file")
-	authorsFile *string = flag.String("authors", "data/authors.json", "authors output file")
-	serve       *bool   = flag.Bool("serve", true, "serve HTTP")
-	httpHost    *string = flag.String("http-host", "0.0.0.0", "HTTP host")
-	httpPort    *int    = flag.Int("http-port", 8585, "HTTP port")
+	dbDriver         *string = flag.String("db-driver", "sqlite3", "database driver: sqlite3, postgres or memory")
+	dbFile           *string = flag.String("db", "pitchdex.db", "database file, or DSN for postgres")
+	jsonFile         *string = flag.String("import", "", "JSON or NDJSON file to import, optionally gzipped (optional)")
+	quarantine       *string = flag.String("quarantine", "", "file to write rejected import records to (optional)")
+	reimport         *bool   = flag.Bool("reimport", false, "reimport existing reviews")
+	rescore          *bool   = flag.Bool("rescore", false, "rescore everything")
+	dictFile         *string = flag.String("dict", "", "dict file (default: the built-in word list)")
+	authorsFile      *string = flag.String("authors", "data/authors.json", "authors output file")
+	serve            *bool   = flag.Bool("serve", true, "serve HTTP")
+	httpHost         *string = flag.String("http-host", "0.0.0.0", "HTTP host")
+	httpPort         *int    = flag.Int("http-port", 8585, "HTTP port")
+	configFile       *string = flag.String("config", "", "index configuration file (optional)")
+	listIndexes      *bool   = flag.Bool("list-indexes", false, "list registered indexes and exit")
+	migrate          *bool   = flag.Bool("migrate", false, "migrate the database schema and exit")
+	score            *bool   = flag.Bool("score", true, "run the scoring pass (otherwise, serve stored scores)")
+	aliasFile        *string = flag.String("aliases", "", "author alias file (optional)")
+	duplicates       *int    = flag.Int("suspect-duplicates", 0, "list authors within N edits of each other and exit")
+	fractionalCredit *bool   = flag.Bool("fractional-credit", false, "credit each of n co-authors with 1/n of a review")
+	stats            *bool   = flag.Bool("stats", false, "print the distribution of every index's stored scores and exit, without scoring")
+	minReviews       *int    = flag.Int("min-reviews", 3, "minimum reviews for an author to be ranked")
+	explain          *int    = flag.Int("explain", 0, "explain the scores of the review with this ID and exit, without scoring")
 )
 
 func main() {
 	flag.Parse()
 
-	// Load
-	db, err := GetDB(*dbFile)
-	if err != nil {
-		log.Fatalf("get database failed: %s", err)
+	// Configure
+	if *listIndexes {
+		for _, idx := range RegisteredIndexes() {
+			fmt.Printf("%s (v%d, %s): %s\n", idx.Name, idx.Version, idx.Direction, idx.Description)
+			for k, v := range idx.Params {
+				fmt.Printf("    %s (default %q)\n", k, v)
+			}
+		}
+		return
 	}
-	if err := Initialize(db); err != nil {
-		log.Fatalf("initialize DB: %s", err)
+	config, err := LoadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("config: %s", err)
 	}
-	log.Printf("reading existing Reviews")
-	reviews, err := SelectAllReviews(db)
+	explainers, err := config.Explainers()
 	if err != nil {
-		log.Fatalf("%s", err)
+		log.Fatalf("config: %s", err)
 	}
-	log.Printf("read %d Reviews from %s", len(reviews), *dbFile)
-	if *jsonFile != "" {
-		log.Printf("importing %s", *jsonFile)
-		if err := reviews.ImportJSON(*jsonFile, *reimport); err != nil {
-			log.Printf("%s", err)
-		}
+	IndexDefinitions = explainers.IndexMap()
+	if Aliases, err = LoadAliases(*aliasFile); err != nil {
+		log.Fatalf("aliases: %s", err)
 	}
-	if len(reviews) <= 0 {
-		log.Fatalf("no reviews loaded")
+	composites := config.CompositeDefinitions()
+	if err := ValidateComposites(composites, IndexDefinitions); err != nil {
+		log.Fatalf("config: %s", err)
+	}
+	inputs, err := config.Inputs()
+	if err != nil {
+		log.Fatalf("config: %s", err)
 	}
-	log.Printf("%d reviews loaded", len(reviews))
 
-	// Calculate review-scores
-	log.Printf("calculating regular scores...")
-	count := 0
-	for indexName, scoringFunc := range IndexDefinitions {
-		for id, review := range reviews {
-			if _, ok := review.Scores[indexName]; !ok || *rescore {
-				reviews[id].Scores[indexName] = scoringFunc(review)
-				count++
-			}
-		}
+	// Load
+	store, err := OpenStore(*dbDriver, *dbFile)
+	if err != nil {
+		log.Fatalf("open store failed: %s", err)
 	}
-	log.Printf("calculating %s...", BullshitScore)
-	allStats := GatherAll(reviews)
-	for id, review := range reviews {
-		if _, ok := review.Scores[BullshitScore]; *rescore || !ok {
-			reviews[id].Scores[BullshitScore] = calculateBullshit(review, allStats)
-			count++
+	defer store.Close()
+	if *migrate {
+		current, err := store.SchemaVersion()
+		if err != nil {
+			log.Fatalf("schema version: %s", err)
 		}
+		log.Printf("%s: schema version %d, target %d", *dbFile, current, TargetSchemaVersion())
+		from, to, err := store.Migrate()
+		if err != nil {
+			log.Fatalf("migrate: %s", err)
+		}
+		if from == to {
+			log.Printf("%s: up to date", *dbFile)
+		} else {
+			log.Printf("%s: migrated from %d to %d", *dbFile, from, to)
+		}
+		return
 	}
-	log.Printf("calculated %d scores", count)
-
-	// Calculate author-scores
-	authors := map[string]map[string]int{} // author -> avg scores
-	for author, count := range reviews.AuthorCount() {
-		authors[author] = map[string]int{
-			"Reviews": count,
-		}
-		ids := reviews.By(func(r Review) bool { return r.Author == author })
-		// The Bullshit of a given review is impacted by global stats.
-		// But the Bullshit of an author is independent of other authors,
-		// in the first-order sense.
-		supplementedIndexDefinitions := IndexDefinitions
-		supplementedIndexDefinitions[BullshitScore] = nil
-		for indexName, _ := range IndexDefinitions {
-			total := 0 // total score for this author for indexName
-			for _, id := range ids {
-				total += reviews[id].Scores[indexName]
-			}
-			averageScore := int(float64(total) / float64(len(ids))) // simple
-			authors[author][indexName] = averageScore
+	if _, _, err := store.Migrate(); err != nil {
+		log.Fatalf("initialize DB: %s", err)
+	}
+	if *duplicates > 0 {
+		reportDuplicates(store, *duplicates)
+		return
+	}
+	if *stats {
+		reportStats(store, composites) // of the stored scores, as they are
+		return
+	}
+	explainer := &Explainer{Store: store, Indexes: explainers, Composites: composites}
+	if *explain > 0 {
+		x, err := explainer.Explain(*explain)
+		if err != nil {
+			log.Fatalf("explain: %s", err)
 		}
+		WriteExplanation(os.Stdout, x)
+		return
 	}
-
-	// Write
-	if err := InsertReviews(db, reviews); err != nil {
+	if *jsonFile != "" {
+		importReviews(store, *jsonFile)
+	}
+	if *score {
+		scoringPass(store, composites, inputs)
+	}
+	authors, err := store.SelectAllAuthorScores()
+	if err != nil {
 		log.Fatalf("%s", err)
 	}
-	if err := WriteAuthors(authors, *authorsFile); err != nil {
+	if err := WriteAuthors(authors, *minReviews, *authorsFile); err != nil {
 		log.Fatalf("%s", err)
 	}
 
@@ -112,6 +139,26 @@ func main() {
 			),
 		)
 	}
+	http.HandleFunc("/data/authors.json", func(w http.ResponseWriter, r *http.Request) {
+		computedAt, err := store.AuthorScoresComputedAt()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !computedAt.IsZero() {
+			w.Header().Set("Last-Modified", computedAt.Format(http.TimeFormat))
+		}
+		authors, err := store.SelectAllAuthorScores()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := EncodeAuthors(w, authors, *minReviews); err != nil {
+			log.Printf("authors.json: %s", err)
+		}
+	})
+	http.Handle("/explain/", explainer)
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		log.Printf(
 			"serving client %s (via %s) -- %s",
@@ -131,3 +178,305 @@ func main() {
 	log.Printf("serving on %s", endpoint)
 	log.Fatalf("%s", http.ListenAndServe(endpoint, nil))
 }
+
+// reportDuplicates prints pairs of authors whose canonical names are within
+// maxDistance edits of each other, as candidates for the alias file.
+func reportDuplicates(store Store, maxDistance int) {
+	reviews, err := store.SelectAllReviews()
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	Aliases.Learn(reviews.RawAuthors())
+	counts := reviews.AuthorCount()
+	names := []string{}
+	for name, _ := range counts {
+		names = append(names, name)
+	}
+	for _, dup := range SuspectedDuplicates(names, maxDistance) {
+		fmt.Printf(
+			"%d\t%q (%d reviews)\t%q (%d reviews)\n",
+			dup.Distance,
+			dup.A,
+			counts[dup.A],
+			dup.B,
+			counts[dup.B],
+		)
+	}
+}
+
+// reportStats prints a summary and histogram of the scores of every
+// registered index, and every composite.
+func reportStats(store Store, composites []Composite) {
+	reviews, err := store.SelectAllReviews()
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	names := []string{}
+	for _, idx := range RegisteredIndexes() {
+		names = append(names, idx.Name)
+	}
+	for _, composite := range composites {
+		names = append(names, composite.Name)
+	}
+	for _, name := range names {
+		values := Values(reviews, name)
+		s := Gather(reviews, name)
+		if s.Instances <= 0 {
+			fmt.Printf("%s: no scores\n\n", name)
+			continue
+		}
+		f := func(v float64) string { return FormatScore(name, v) }
+		fmt.Printf("%s: %d reviews\n", name, s.Instances)
+		fmt.Printf(
+			"    min %s  p25 %s  median %s  p75 %s  max %s\n",
+			f(s.Minimum), f(s.P25), f(s.Median), f(s.P75), f(s.Maximum),
+		)
+		fmt.Printf(
+			"    mean %.2f  stddev %.2f  MAD %.2f\n",
+			s.Mean, s.StandardDeviation, s.MAD,
+		)
+		bins, most := Histogram(values, 10), 0
+		for _, bin := range bins {
+			if bin.Count > most {
+				most = bin.Count
+			}
+		}
+		for _, bin := range bins {
+			line := fmt.Sprintf(
+				"    %10s - %-10s %7d %s",
+				f(bin.Low),
+				f(bin.High),
+				bin.Count,
+				strings.Repeat("#", bin.Count*40/most),
+			)
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+		fmt.Println()
+	}
+}
+
+// importReviews streams reviews from filename into the store, resuming an
+// earlier interrupted import of the same file.
+func importReviews(store Store, filename string) {
+	log.Printf("importing %s", filename)
+	importer := Importer{
+		Store:      store,
+		Reimport:   *reimport,
+		Checkpoint: filename + ".checkpoint",
+	}
+	if *quarantine != "" {
+		f, err := os.OpenFile(*quarantine, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("quarantine: %s", err)
+		}
+		defer f.Close()
+		importer.Quarantine = f
+	}
+	report, err := importer.Import(filename)
+	for _, e := range report.Errors {
+		log.Printf("skipped %s", e)
+	}
+	if report.Resumed > 0 {
+		log.Printf("resumed after %d records", report.Resumed)
+	}
+	log.Printf(
+		"imported %d reviews (%d already present, %d skipped)",
+		report.Imported,
+		report.Existing,
+		len(report.Errors),
+	)
+	if err != nil {
+		log.Fatalf("import %s: %s", filename, err)
+	}
+}
+
+// scoringPass scores and stores every review, then every author. Only
+// missing and stale scores are computed, unless -rescore is set.
+func scoringPass(store Store, composites []Composite, inputs map[string]string) {
+	log.Printf("reading existing Reviews")
+	reviews, err := store.SelectAllReviews()
+	if err != nil {
+		log.Fatalf("%s", err)
+	}
+	log.Printf("read %d Reviews from %s", len(reviews), *dbFile)
+	if len(reviews) <= 0 {
+		log.Fatalf("no reviews loaded")
+	}
+	log.Printf("%d reviews loaded", len(reviews))
+	Aliases.Learn(reviews.RawAuthors())
+
+	changed := changedInputs(reviews, inputs)
+	names := []string{}
+	for name, _ := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		olds := []string{}
+		for old, _ := range changed[name] {
+			olds = append(olds, old)
+		}
+		sort.Strings(olds)
+		for _, old := range olds {
+			log.Printf(
+				"%s: %d scores were computed with %s, now %s",
+				name,
+				changed[name][old],
+				describeInput(old),
+				describeInput(inputs[name]),
+			)
+		}
+	}
+
+	// Calculate review-scores
+	log.Printf("calculating scores...")
+	report := scoreReviews(reviews, IndexDefinitions, composites, inputs, *rescore)
+	names, count := []string{}, 0
+	for name, n := range report.Computed {
+		names = append(names, name)
+		count += n
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if n := report.Refreshed[name]; n > 0 {
+			log.Printf("%s: refreshed %d stale scores, computed with another version or input", name, n)
+		}
+		if n := report.Forced[name]; n > 0 {
+			log.Printf("%s: recomputed %d current scores, per -rescore", name, n)
+		}
+		if n := report.Recomputed[name]; n > 0 {
+			log.Printf("%s: recomputed %d current scores, as index scores changed", name, n)
+		}
+	}
+	log.Printf("calculated %d scores", count)
+
+	// Calculate author-scores
+	// The Bullshit of a given review is impacted by global stats.
+	// But the Bullshit of an author is independent of other authors,
+	// in the first-order sense.
+	indexNames := []string{}
+	for indexName, _ := range IndexDefinitions {
+		if indexName != "Reviews" { // counted separately
+			indexNames = append(indexNames, indexName)
+		}
+	}
+	for _, composite := range composites {
+		indexNames = append(indexNames, composite.Name)
+	}
+	authors := reviews.AuthorScores(indexNames, *fractionalCredit)
+
+	// Write
+	if err := store.InsertReviews(reviews); err != nil {
+		log.Fatalf("%s", err)
+	}
+	if err := store.PruneAuthors(); err != nil {
+		log.Fatalf("%s", err)
+	}
+	if err := store.InsertAuthorScores(authors, true); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
+
+// A ScoringReport counts the scores computed by scoreReviews, by name.
+type ScoringReport struct {
+	Computed   map[string]int
+	Refreshed  map[string]int // stale scores replaced
+	Forced     map[string]int // current scores replaced, per rescore
+	Recomputed map[string]int // current composites replaced, as index scores changed
+}
+
+// scoreReviews computes every review's missing and stale scores: those
+// whose Provenance differs from the current one, or all of them if rescore
+// is set. Composites depend on every review's scores, so they're all
+// recomputed if any index score was.
+func scoreReviews(
+	reviews Reviews,
+	indexes IndexMap,
+	composites []Composite,
+	inputs map[string]string,
+	rescore bool,
+) ScoringReport {
+	report := ScoringReport{map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}}
+	digests := map[int]string{}
+	for id, review := range reviews {
+		digests[id] = Digest(review.Body)
+		if review.Scores == nil {
+			review.Scores = map[string]float64{}
+		}
+		if review.Provenance == nil {
+			review.Provenance = map[string]Provenance{}
+		}
+		reviews[id] = review
+	}
+	// update computes a score if it's missing or stale, or if force is set,
+	// counting the current scores it replaces in forced.
+	update := func(id int, name string, p Provenance, score func(Review) float64, force bool, forced map[string]int) {
+		review := reviews[id]
+		_, ok := review.Scores[name]
+		switch current := ok && review.Provenance[name] == p; {
+		case current && !force:
+			return
+		case current:
+			forced[name]++
+		case ok:
+			report.Refreshed[name]++
+		}
+		review.Scores[name] = score(review)
+		review.Provenance[name] = p
+		report.Computed[name]++
+	}
+
+	indexCount := 0
+	for indexName, scoringFunc := range indexes {
+		version := 0
+		if idx, ok := LookupIndex(indexName); ok {
+			version = idx.Version
+		}
+		for id, _ := range reviews {
+			update(id, indexName, NewProvenance(version, inputs[indexName], digests[id]), scoringFunc, rescore, report.Forced)
+		}
+		indexCount += report.Computed[indexName]
+	}
+	allStats := GatherAll(reviews)
+	for _, composite := range composites {
+		p := NewProvenance(0, "", Digest(composite.definition()))
+		score := func(review Review) float64 { return composite.Score(review, allStats) }
+		for id, _ := range reviews {
+			if rescore {
+				update(id, composite.Name, p, score, true, report.Forced)
+			} else {
+				update(id, composite.Name, p, score, indexCount > 0, report.Recomputed)
+			}
+		}
+	}
+	return report
+}
+
+// changedInputs counts, by index name and stored InputID, the scores that
+// were computed with other inputs than the current ones.
+func changedInputs(reviews Reviews, current map[string]string) map[string]map[string]int {
+	changed := map[string]map[string]int{}
+	for _, review := range reviews {
+		for name, input := range current {
+			if _, ok := review.Scores[name]; !ok {
+				continue
+			}
+			if old := review.Provenance[name].Input; old != input {
+				if changed[name] == nil {
+					changed[name] = map[string]int{}
+				}
+				changed[name][old]++
+			}
+		}
+	}
+	return changed
+}
+
+// describeInput describes an InputID for the log. Scores from before
+// Provenance was recorded have none.
+func describeInput(input string) string {
+	if input == "" {
+		return "unrecorded inputs"
+	}
+	return input
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store that keeps everything in memory. It's meant for
+// tests, and for scoring runs that shouldn't touch a database.
+type MemoryStore struct {
+	mu           sync.Mutex
+	reviews      Reviews // without Scores or Provenance
+	scores       map[int]map[string]float64
+	provenance   map[int]map[string]Provenance
+	authorScores map[string]map[string]AuthorScore
+	computedAt   time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		reviews:      Reviews{},
+		scores:       map[int]map[string]float64{},
+		provenance:   map[int]map[string]Provenance{},
+		authorScores: map[string]map[string]AuthorScore{},
+	}
+}
+
+func (s *MemoryStore) SchemaVersion() (int, error) { return TargetSchemaVersion(), nil }
+
+func (s *MemoryStore) Migrate() (int, int, error) {
+	return TargetSchemaVersion(), TargetSchemaVersion(), nil
+}
+
+func (s *MemoryStore) SelectReviews(ids []int) (Reviews, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reviews := Reviews{}
+	for _, id := range ids {
+		review, ok := s.reviews[id]
+		if !ok {
+			continue
+		}
+		review.Scores = copyScores(s.scores[id])
+		review.Provenance = map[string]Provenance{}
+		for k, v := range s.provenance[id] {
+			review.Provenance[k] = v
+		}
+		reviews[id] = review
+	}
+	return reviews, nil
+}
+
+func (s *MemoryStore) SelectAllReviews() (Reviews, error) {
+	s.mu.Lock()
+	ids := make([]int, 0, len(s.reviews))
+	for id, _ := range s.reviews {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	return s.SelectReviews(ids)
+}
+
+// IterateReviews iterates over a snapshot of the reviews.
+func (s *MemoryStore) IterateReviews() ReviewIterator {
+	reviews, _ := s.SelectAllReviews()
+	ids := make([]int, 0, len(reviews))
+	for id, _ := range reviews {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	it := &sliceReviewIterator{i: -1}
+	for _, id := range ids {
+		it.reviews = append(it.reviews, reviews[id])
+	}
+	return it
+}
+
+type sliceReviewIterator struct {
+	reviews []Review
+	i       int
+}
+
+func (it *sliceReviewIterator) Next() bool     { it.i++; return it.i < len(it.reviews) }
+func (it *sliceReviewIterator) Review() Review { return it.reviews[it.i] }
+func (it *sliceReviewIterator) Err() error     { return nil }
+
+func (s *MemoryStore) SelectBodys(ids []int) (map[int]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := map[int]string{}
+	for _, id := range ids {
+		if review, ok := s.reviews[id]; ok {
+			m[id] = review.Body
+		}
+	}
+	return m, nil
+}
+
+func (s *MemoryStore) InsertReviews(reviews Reviews) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, review := range reviews {
+		review.Scores, review.Provenance = nil, nil
+		s.reviews[id] = review
+		s.upsertScores(id, reviews[id].Scores, reviews[id].Provenance, true)
+	}
+	return nil
+}
+
+func (s *MemoryStore) InsertReviewScores(scores map[int]map[string]float64, overwrite bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, scoreMap := range scores {
+		s.upsertScores(id, scoreMap, nil, overwrite)
+	}
+	return nil
+}
+
+func (s *MemoryStore) upsertScores(id int, scoreMap map[string]float64, provenance map[string]Provenance, overwrite bool) {
+	if _, ok := s.scores[id]; !ok {
+		s.scores[id] = map[string]float64{}
+		s.provenance[id] = map[string]Provenance{}
+	}
+	for scoreName, scoreValue := range scoreMap {
+		if _, ok := s.scores[id][scoreName]; ok && !overwrite {
+			continue
+		}
+		s.scores[id][scoreName] = scoreValue
+		s.provenance[id][scoreName] = provenance[scoreName]
+	}
+}
+
+func (s *MemoryStore) InsertAuthorScores(scores map[string]map[string]AuthorScore, overwrite bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for author, scoreMap := range scores {
+		if _, ok := s.authorScores[author]; !ok {
+			s.authorScores[author] = map[string]AuthorScore{}
+		}
+		for scoreName, scoreValue := range scoreMap {
+			if _, ok := s.authorScores[author][scoreName]; ok && !overwrite {
+				continue
+			}
+			s.authorScores[author][scoreName] = scoreValue
+		}
+	}
+	s.computedAt = time.Now().UTC().Truncate(time.Second)
+	return nil
+}
+
+func (s *MemoryStore) PruneAuthors() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	credited := map[string]bool{}
+	for _, review := range s.reviews {
+		for _, author := range review.Authors() {
+			credited[author] = true
+		}
+	}
+	for author, _ := range s.authorScores {
+		if !credited[author] {
+			delete(s.authorScores, author)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) SelectAllAuthorScores() (map[string]map[string]AuthorScore, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	authors := map[string]map[string]AuthorScore{}
+	for author, scoreMap := range s.authorScores {
+		authors[author] = map[string]AuthorScore{}
+		for k, v := range scoreMap {
+			authors[author][k] = v
+		}
+	}
+	return authors, nil
+}
+
+func (s *MemoryStore) AuthorScoresComputedAt() (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.computedAt, nil
+}
+
+func (s *MemoryStore) Close() error { return nil }
+
+func copyScores(m map[string]float64) map[string]float64 {
+	c := make(map[string]float64, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// A Migration takes the database schema from Version-1 to Version.
+type Migration struct {
+	Version     int
+	Description string
+	Statements  []string // SQLite
+	Postgres    []string
+	Func        func(db *DB, tx *sql.Tx) error // after the statements; optional
+}
+
+// Migrations must be in Version order, starting at 1, without gaps. Never
+// edit a Migration once it's been released; add a new one.
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "initial schema",
+		// IF NOT EXISTS adopts databases created before schema_version.
+		Statements: []string{
+			"CREATE TABLE IF NOT EXISTS reviews (id INT PRIMARY KEY, body TEXT)",
+			"CREATE TABLE IF NOT EXISTS authors (name TEXT PRIMARY KEY)",
+			"CREATE TABLE IF NOT EXISTS review_scores (review_id INT, name STRING, score INT)",
+			"CREATE TABLE IF NOT EXISTS author_scores (author_name STRING, name STRING, score INT)",
+			"CREATE TABLE IF NOT EXISTS authorship (review_id INT, author_name STRING)",
+			"CREATE INDEX IF NOT EXISTS review_score_name ON review_scores (name)",
+			"CREATE INDEX IF NOT EXISTS author_score_name ON author_scores (name)",
+			"CREATE INDEX IF NOT EXISTS review_score_nsc ON review_scores (name, score)",
+			"CREATE INDEX IF NOT EXISTS author_score_nsc ON author_scores (name, score)",
+		},
+		Postgres: []string{
+			"CREATE TABLE reviews (id INTEGER PRIMARY KEY, body TEXT)",
+			"CREATE TABLE authors (name TEXT PRIMARY KEY)",
+			"CREATE TABLE review_scores (review_id INTEGER, name TEXT, score INTEGER)",
+			"CREATE TABLE author_scores (author_name TEXT, name TEXT, score INTEGER)",
+			"CREATE TABLE authorship (review_id INTEGER, author_name TEXT)",
+			"CREATE INDEX review_score_name ON review_scores (name)",
+			"CREATE INDEX author_score_name ON author_scores (name)",
+			"CREATE INDEX review_score_nsc ON review_scores (name, score)",
+			"CREATE INDEX author_score_nsc ON author_scores (name, score)",
+		},
+	},
+	{
+		Version:     2,
+		Description: "review permalinks and metadata",
+		Statements: []string{
+			"ALTER TABLE reviews ADD COLUMN permalink TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN artist TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN album TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN label TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN release_year INT NOT NULL DEFAULT 0",
+			"ALTER TABLE reviews ADD COLUMN published TEXT NOT NULL DEFAULT ''", // YYYY-MM-DD
+			"ALTER TABLE reviews ADD COLUMN rating REAL NOT NULL DEFAULT 0",
+			"ALTER TABLE reviews ADD COLUMN best_new_music INT NOT NULL DEFAULT 0",
+		},
+		Postgres: []string{
+			"ALTER TABLE reviews ADD COLUMN permalink TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN artist TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN album TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN label TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN release_year INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE reviews ADD COLUMN published TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE reviews ADD COLUMN rating DOUBLE PRECISION NOT NULL DEFAULT 0",
+			"ALTER TABLE reviews ADD COLUMN best_new_music BOOLEAN NOT NULL DEFAULT FALSE",
+		},
+	},
+	{
+		Version:     3,
+		Description: "unique keys for upserts",
+		// Keep the most recently written of any duplicates.
+		Statements: []string{
+			`DELETE FROM review_scores WHERE rowid NOT IN (
+				SELECT MAX(rowid) FROM review_scores GROUP BY review_id, name
+			)`,
+			`DELETE FROM author_scores WHERE rowid NOT IN (
+				SELECT MAX(rowid) FROM author_scores GROUP BY author_name, name
+			)`,
+			`DELETE FROM authorship WHERE rowid NOT IN (
+				SELECT MAX(rowid) FROM authorship GROUP BY review_id, author_name
+			)`,
+			"CREATE UNIQUE INDEX review_score_key ON review_scores (review_id, name)",
+			"CREATE UNIQUE INDEX author_score_key ON author_scores (author_name, name)",
+			"CREATE UNIQUE INDEX authorship_key ON authorship (review_id, author_name)",
+		},
+		// PostgreSQL support postdates any duplicates.
+		Postgres: []string{
+			"CREATE UNIQUE INDEX review_score_key ON review_scores (review_id, name)",
+			"CREATE UNIQUE INDEX author_score_key ON author_scores (author_name, name)",
+			"CREATE UNIQUE INDEX authorship_key ON authorship (review_id, author_name)",
+		},
+	},
+	{
+		Version:     4,
+		Description: "author score timestamps",
+		Statements: []string{
+			"ALTER TABLE author_scores ADD COLUMN computed_at TEXT NOT NULL DEFAULT ''", // RFC3339
+		},
+		Postgres: []string{
+			"ALTER TABLE author_scores ADD COLUMN computed_at TEXT NOT NULL DEFAULT ''",
+		},
+	},
+	{
+		Version:     5,
+		Description: "reviews by multiple authors",
+		Statements: []string{
+			"ALTER TABLE reviews ADD COLUMN byline TEXT NOT NULL DEFAULT ''",
+			`UPDATE reviews SET byline = COALESCE(
+				(SELECT MIN(author_name) FROM authorship WHERE review_id = reviews.id),
+				''
+			)`,
+		},
+		Postgres: []string{
+			"ALTER TABLE reviews ADD COLUMN byline TEXT NOT NULL DEFAULT ''",
+			`UPDATE reviews SET byline = COALESCE(
+				(SELECT MIN(author_name) FROM authorship WHERE review_id = reviews.id),
+				''
+			)`,
+		},
+		Func: splitAuthorship,
+	},
+	{
+		Version:     6,
+		Description: "real-valued scores",
+		// SQLite can't change a column's type; rebuild the tables instead.
+		Statements: []string{
+			"CREATE TABLE review_scores_real (review_id INT, name TEXT, score REAL)",
+			"INSERT INTO review_scores_real SELECT review_id, name, CAST(score AS REAL) FROM review_scores",
+			"DROP TABLE review_scores",
+			"ALTER TABLE review_scores_real RENAME TO review_scores",
+			"CREATE INDEX review_score_name ON review_scores (name)",
+			"CREATE INDEX review_score_nsc ON review_scores (name, score)",
+			"CREATE UNIQUE INDEX review_score_key ON review_scores (review_id, name)",
+			"CREATE TABLE author_scores_real (author_name TEXT, name TEXT, score REAL, computed_at TEXT NOT NULL DEFAULT '')",
+			"INSERT INTO author_scores_real SELECT author_name, name, CAST(score AS REAL), computed_at FROM author_scores",
+			"DROP TABLE author_scores",
+			"ALTER TABLE author_scores_real RENAME TO author_scores",
+			"CREATE INDEX author_score_name ON author_scores (name)",
+			"CREATE INDEX author_score_nsc ON author_scores (name, score)",
+			"CREATE UNIQUE INDEX author_score_key ON author_scores (author_name, name)",
+		},
+		Postgres: []string{
+			"ALTER TABLE review_scores ALTER COLUMN score TYPE DOUBLE PRECISION",
+			"ALTER TABLE author_scores ALTER COLUMN score TYPE DOUBLE PRECISION",
+		},
+	},
+	{
+		Version:     7,
+		Description: "author score means and confidence intervals",
+		// Existing scores are plain means.
+		Statements: []string{
+			"ALTER TABLE author_scores ADD COLUMN mean REAL NOT NULL DEFAULT 0",
+			"ALTER TABLE author_scores ADD COLUMN low REAL NOT NULL DEFAULT 0",
+			"ALTER TABLE author_scores ADD COLUMN high REAL NOT NULL DEFAULT 0",
+			"UPDATE author_scores SET mean = score, low = score, high = score",
+		},
+		Postgres: []string{
+			"ALTER TABLE author_scores ADD COLUMN mean DOUBLE PRECISION NOT NULL DEFAULT 0",
+			"ALTER TABLE author_scores ADD COLUMN low DOUBLE PRECISION NOT NULL DEFAULT 0",
+			"ALTER TABLE author_scores ADD COLUMN high DOUBLE PRECISION NOT NULL DEFAULT 0",
+			"UPDATE author_scores SET mean = score, low = score, high = score",
+		},
+	},
+	{
+		Version:     8,
+		Description: "score provenance",
+		// Existing scores have the zero Provenance, and will be rescored.
+		Statements: []string{
+			"ALTER TABLE review_scores ADD COLUMN version INT NOT NULL DEFAULT 0",
+			"ALTER TABLE review_scores ADD COLUMN input TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE review_scores ADD COLUMN hash TEXT NOT NULL DEFAULT ''",
+		},
+		Postgres: []string{
+			"ALTER TABLE review_scores ADD COLUMN version INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE review_scores ADD COLUMN input TEXT NOT NULL DEFAULT ''",
+			"ALTER TABLE review_scores ADD COLUMN hash TEXT NOT NULL DEFAULT ''",
+		},
+	},
+}
+
+// splitAuthorship credits each author of a co-written review individually,
+// rather than crediting their combined byline.
+func splitAuthorship(db *DB, tx *sql.Tx) error {
+	type credit struct {
+		reviewID int
+		byline   string
+	}
+	credits := []credit{}
+	rows, err := tx.Query("SELECT review_id, author_name FROM authorship")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var c credit
+		if err := rows.Scan(&c.reviewID, &c.byline); err != nil {
+			rows.Close()
+			return err
+		}
+		credits = append(credits, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range credits {
+		authors := ParseReviewers(c.byline)
+		if len(authors) == 1 && authors[0] == c.byline {
+			continue
+		}
+		if _, err := tx.Exec(
+			db.rebind("DELETE FROM authorship WHERE review_id = ? AND author_name = ?"),
+			c.reviewID,
+			c.byline,
+		); err != nil {
+			return err
+		}
+		for _, author := range authors {
+			if _, err := tx.Exec(
+				db.rebind("INSERT INTO authors VALUES (?) ON CONFLICT (name) DO NOTHING"),
+				author,
+			); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(
+				db.rebind(`INSERT INTO authorship VALUES (?, ?)
+				 ON CONFLICT (review_id, author_name) DO NOTHING`),
+				c.reviewID,
+				author,
+			); err != nil {
+				return err
+			}
+		}
+	}
+	for _, statement := range []string{
+		"DELETE FROM authors WHERE name NOT IN (SELECT author_name FROM authorship)",
+		"DELETE FROM author_scores WHERE author_name NOT IN (SELECT name FROM authors)",
+	} {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TargetSchemaVersion() int {
+	return Migrations[len(Migrations)-1].Version
+}
+
+// SchemaVersion returns the version of the database schema; 0 if it has
+// never been migrated.
+func SchemaVersion(db *DB) (int, error) {
+	if _, err := db.Exec(
+		"CREATE TABLE IF NOT EXISTS schema_version (version INT PRIMARY KEY, description TEXT, applied_at TEXT)",
+	); err != nil {
+		return 0, err
+	}
+	var version int
+	row := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version")
+	if err := row.Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Migrate applies every Migration newer than the database schema, each in
+// its own transaction, and returns the versions it migrated from and to.
+func Migrate(db *DB) (int, int, error) {
+	from, err := SchemaVersion(db)
+	if err != nil {
+		return 0, 0, fmt.Errorf("read schema version: %s", err)
+	}
+	if from > TargetSchemaVersion() {
+		return from, from, fmt.Errorf(
+			"schema version %d is newer than this binary supports (%d)",
+			from,
+			TargetSchemaVersion(),
+		)
+	}
+	to := from
+	for _, m := range Migrations {
+		if m.Version <= from {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return from, to, fmt.Errorf("migration %d (%s): %s", m.Version, m.Description, err)
+		}
+		to = m.Version
+	}
+	return from, to, nil
+}
+
+func applyMigration(db *DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	statements := m.Statements
+	if db.dialect == postgresDialect {
+		statements = m.Postgres
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("%s: %s", statement, err)
+		}
+	}
+	if m.Func != nil {
+		if err := m.Func(db, tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec(
+		db.rebind("INSERT INTO schema_version VALUES (?, ?, ?)"),
+		m.Version,
+		m.Description,
+		time.Now().UTC().Format(time.RFC3339),
+	); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
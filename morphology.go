@@ -0,0 +1,117 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// suffixes are inflectional and derivational endings, each with what to put
+// back in its place to recover the base word: "happily" is "happy" + "ly".
+var suffixes = [][2]string{
+	{"n't", ""}, {"'ll", ""}, {"'re", ""}, {"'ve", ""}, {"'d", ""}, {"'m", ""},
+	{"ies", "y"}, {"ied", "y"}, {"ier", "y"}, {"iest", "y"}, {"ily", "y"}, {"iness", "y"},
+	{"ably", "able"}, {"ibly", "ible"},
+	{"es", ""}, {"s", ""},
+	{"ed", ""}, {"ed", "e"}, {"ing", ""}, {"ing", "e"},
+	{"er", ""}, {"er", "e"}, {"est", ""}, {"est", "e"},
+	{"ly", ""}, {"ness", ""}, {"less", ""}, {"ful", ""}, {"ment", ""},
+	{"able", ""}, {"able", "e"}, {"ish", ""}, {"ism", ""}, {"ist", ""},
+	{"ity", ""}, {"ize", ""}, {"esque", ""}, {"y", ""}, {"y", "e"},
+}
+
+// prefixes are stripped whole: "unhurried" is "un" + "hurried".
+var prefixes = []string{
+	"anti", "co", "counter", "de", "dis", "hyper", "inter", "mis", "multi",
+	"neo", "non", "out", "over", "post", "pre", "proto", "pseudo", "re",
+	"semi", "sub", "super", "ultra", "un", "under",
+}
+
+// Known reports whether word is in the Dict in any form: as is, possessive,
+// inflected or derived by common affixes ("shimmered", "unhurried"), or
+// compounded of known words ("post-rock", "songcraft"). Affixes are
+// stripped at most twice ("unpredictably").
+func (d Dict) Known(word string) bool {
+	return d.known(word, 2)
+}
+
+func (d Dict) known(word string, depth int) bool {
+	if d.Has(word) {
+		return true
+	}
+	if base := strings.TrimSuffix(word, "'s"); base != word {
+		return d.known(base, depth)
+	}
+	if base := strings.TrimSuffix(word, "'"); base != word {
+		return d.known(base, depth) // "bands'"
+	}
+	if strings.Contains(word, "-") {
+		for _, part := range strings.Split(word, "-") {
+			if part == "" || !d.known(part, depth) {
+				return false
+			}
+		}
+		return true
+	}
+	if depth <= 0 {
+		return false
+	}
+	for _, base := range bases(word) {
+		if d.known(base, depth-1) {
+			return true
+		}
+	}
+	for _, prefix := range prefixes {
+		if rest := strings.TrimPrefix(word, prefix); rest != word && len(rest) >= 3 && d.known(rest, depth-1) {
+			return true
+		}
+	}
+	// Closed compounds, of two words as they are
+	for i := 3; i <= len(word)-3; i++ {
+		if d.Has(word[:i]) && d.Has(word[i:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// bases returns the words that word may be formed from by a suffix.
+// Doubled consonants are undone: "stopped" may be "stop" + "ed".
+func bases(word string) []string {
+	candidates := []string{}
+	for _, suffix := range suffixes {
+		base := strings.TrimSuffix(word, suffix[0])
+		if base == word || len(base) < 2 {
+			continue
+		}
+		candidates = append(candidates, base+suffix[1])
+		if n := len(base); suffix[1] == "" && n >= 3 && base[n-1] == base[n-2] {
+			candidates = append(candidates, base[:n-1])
+		}
+	}
+	return candidates
+}
+
+// properNouns returns the start offsets of the tokens that are taken to be
+// names: capitalized words that don't begin a sentence.
+func properNouns(body string, tokens []Token) map[int]bool {
+	text, spans := blockTextSpans(body)
+	starts := []int{}
+	for _, s := range Sentences(text) {
+		starts = append(starts, spans[s.Start].start)
+	}
+	names, k := map[int]bool{}, 0
+	for _, tok := range tokens {
+		initial := false
+		for k < len(starts) && starts[k] <= tok.Start {
+			initial = true
+			k++
+		}
+		if initial {
+			continue
+		}
+		if chars := decodeText(body[tok.Start:tok.End], 0); len(chars) > 0 && unicode.IsUpper(chars[0].r) {
+			names[tok.Start] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// A Normalization puts an index's raw scores on a common scale, given the
+// distribution of that index's scores, so that they can be weighted against
+// other indexes in a composite.
+type Normalization interface {
+	Normalize(score float64, stats StatisticalData) float64
+}
+
+// DefaultNormalization is used for indexes that don't configure one.
+const DefaultNormalization = "bucket"
+
+var normalizations = map[string]Normalization{
+	"bucket":     BucketNormalization{},
+	"zscore":     ZScoreNormalization{},
+	"percentile": PercentileNormalization{},
+	"minmax":     MinMaxNormalization{},
+	"robust":     RobustNormalization{},
+}
+
+func LookupNormalization(name string) (Normalization, bool) {
+	n, ok := normalizations[name]
+	return n, ok
+}
+
+func normalizationNames() string {
+	names := []string{}
+	for name, _ := range normalizations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+//
+//
+//
+
+// BucketNormalization counts the whole standard deviations, 1 to 10, that a
+// score lies above the minimum. A single outlier inflates the standard
+// deviation, and crowds every other score into the first bucket or two.
+type BucketNormalization struct{}
+
+func (BucketNormalization) Normalize(score float64, stats StatisticalData) float64 {
+	return float64(DeviationsFromMinimum(score, stats))
+}
+
+// ZScoreNormalization is the number of standard deviations a score lies
+// from the mean.
+type ZScoreNormalization struct{}
+
+func (ZScoreNormalization) Normalize(score float64, stats StatisticalData) float64 {
+	if stats.StandardDeviation == 0 {
+		return 0
+	}
+	return (score - stats.Mean) / stats.StandardDeviation
+}
+
+// PercentileNormalization is the percentile rank of a score, from 0 to 10.
+// Ties share their mid-rank.
+type PercentileNormalization struct{}
+
+func (PercentileNormalization) Normalize(score float64, stats StatisticalData) float64 {
+	n := len(stats.Values)
+	if n <= 0 {
+		return 0
+	}
+	below := sort.SearchFloat64s(stats.Values, score)
+	upTo := sort.Search(n, func(i int) bool { return stats.Values[i] > score })
+	return 10 * (float64(below) + float64(upTo-below)/2) / float64(n)
+}
+
+// MinMaxNormalization scales scores linearly from 0, the minimum, to 10,
+// the maximum.
+type MinMaxNormalization struct{}
+
+func (MinMaxNormalization) Normalize(score float64, stats StatisticalData) float64 {
+	if stats.Maximum == stats.Minimum {
+		return 0
+	}
+	return 10 * (score - stats.Minimum) / (stats.Maximum - stats.Minimum)
+}
+
+// RobustNormalization is a z-score that uses the median and MAD in place of
+// the mean and standard deviation, so outliers barely move it. The MAD is
+// scaled to match the standard deviation of normally distributed scores.
+// If more than half the scores are identical, the MAD is 0, and the
+// standard deviation stands in for it.
+type RobustNormalization struct{}
+
+func (RobustNormalization) Normalize(score float64, stats StatisticalData) float64 {
+	spread := 1.4826 * stats.MAD
+	if spread == 0 {
+		spread = stats.StandardDeviation
+	}
+	if spread == 0 {
+		return 0
+	}
+	return (score - stats.Median) / spread
+}
+
+// DeviationsFromMinimum returns the number of whole standard deviations,
+// from 1 to 10, that score lies above the minimum.
+func DeviationsFromMinimum(score float64, stats StatisticalData) int {
+	for i := 1; i < 10; i++ {
+		if score <= stats.Minimum+(float64(i)*stats.StandardDeviation) {
+			return i
+		}
+	}
+	return 10
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"testing"
+)
+
+// skewed is 99 unremarkable scores, 1 to 99, and one wild outlier.
+func skewed() StatisticalData {
+	reviews := Reviews{}
+	for i := 1; i < 100; i++ {
+		reviews[i] = Review{ID: i, Scores: map[string]float64{"Foo": float64(i)}}
+	}
+	reviews[100] = Review{ID: 100, Scores: map[string]float64{"Foo": 10000}}
+	return Gather(reviews, "Foo")
+}
+
+func TestNormalizationsMonotonic(t *testing.T) {
+	stats := skewed()
+	for name, n := range normalizations {
+		prev := math.Inf(-1)
+		for _, v := range stats.Values {
+			got := n.Normalize(v, stats)
+			if math.IsNaN(got) || got < prev {
+				t.Errorf("%s: %v normalized to %v, after %v", name, v, got, prev)
+			}
+			prev = got
+		}
+	}
+}
+
+// The bucket method can't tell the 25th review from the 75th once an
+// outlier has inflated the standard deviation; the others can.
+func TestNormalizationsOnSkewedDistribution(t *testing.T) {
+	stats := skewed()
+	spread := map[string]float64{}
+	for name, n := range normalizations {
+		spread[name] = n.Normalize(75, stats) - n.Normalize(25, stats)
+	}
+	if spread["bucket"] != 0 {
+		t.Errorf("bucket: expected no spread, got %.2f", spread["bucket"])
+	}
+	for name, min := range map[string]float64{
+		"percentile": 4.9, // half the reviews lie between, on a 0-10 scale
+		"robust":     1,   // more than a standard deviation apart
+	} {
+		if spread[name] < min {
+			t.Errorf("%s: 25 and 75 normalize %.2f apart, expected at least %.2f", name, spread[name], min)
+		}
+	}
+	for _, name := range []string{"zscore", "minmax"} {
+		if spread[name] <= 0 || spread[name] > 0.2 {
+			t.Errorf("%s: 25 and 75 normalize %.2f apart, expected a sliver", name, spread[name])
+		}
+	}
+}
+
+func TestNormalizationRanges(t *testing.T) {
+	stats := skewed()
+	for _, name := range []string{"percentile", "minmax", "bucket"} {
+		n, _ := LookupNormalization(name)
+		got := []float64{}
+		for _, v := range []float64{1, 50, 10000} {
+			got = append(got, n.Normalize(v, stats))
+		}
+		if !sort.Float64sAreSorted(got) || got[0] < 0 || got[2] > 10 {
+			t.Errorf("%s: got %v, expected 0 to 10", name, got)
+		}
+	}
+
+	constant := Gather(Reviews{1: Review{Scores: map[string]float64{"Foo": 3}}}, "Foo")
+	for name, n := range normalizations {
+		if got := n.Normalize(3, constant); math.IsNaN(got) || math.IsInf(got, 0) {
+			t.Errorf("%s: constant scores normalized to %v", name, got)
+		}
+	}
+}
+
+func TestConfiguredNormalization(t *testing.T) {
+	filename := writeConfig(t, `{"indexes": {"Word count": {"normalization": "percentile"}}}`)
+	defer os.Remove(filename)
+	c, err := LoadConfig(filename)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	for _, composite := range c.CompositeDefinitions() {
+		if got := fmt.Sprintf("%T", composite.normalization("Word count")); got != "main.PercentileNormalization" {
+			t.Errorf("%s: Word count normalized by %s", composite.Name, got)
+		}
+		if got := fmt.Sprintf("%T", composite.normalization("Pitchformulaity")); got != "main.BucketNormalization" {
+			t.Errorf("%s: Pitchformulaity normalized by %s", composite.Name, got)
+		}
+	}
+
+	filename = writeConfig(t, `{"indexes": {"Word count": {"normalization": "log"}}}`)
+	defer os.Remove(filename)
+	if _, err := LoadConfig(filename); err == nil {
+		t.Errorf("unknown normalization: expected error, got none")
+	}
+}
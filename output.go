@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 )
 
@@ -28,7 +29,7 @@ func WriteReviews(reviews Reviews, filename string) error {
 			"Author": review.Author,
 		}
 		for indexName, score := range review.Scores {
-			m[indexName] = fmt.Sprintf("%d", score)
+			m[indexName] = FormatScore(indexName, score)
 		}
 		rs.Reviews[i] = m
 		i++
@@ -46,38 +47,52 @@ func WriteReviews(reviews Reviews, filename string) error {
 	return nil
 }
 
-func WriteAuthors(authors map[string]map[string]int, filename string) error {
-	// Create the file
+func WriteAuthors(authors map[string]map[string]AuthorScore, minReviews int, filename string) error {
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
+	return EncodeAuthors(f, authors, minReviews)
+}
 
+// EncodeAuthors writes authors credited on at least minReviews reviews as
+// DataTables JSON. Each index has three columns: the shrunk score, under
+// the index's name; "(mean)", the raw mean; and "(95% CI)", the confidence
+// interval of the mean.
+func EncodeAuthors(w io.Writer, authors map[string]map[string]AuthorScore, minReviews int) error {
 	// Build the in-memory structure
 	type AuthorsStructure struct {
 		Authors []map[string]string `json:"aaData"`
 	}
-	count := len(authors)
-	i, as := 0, AuthorsStructure{make([]map[string]string, count)}
+	as := AuthorsStructure{[]map[string]string{}}
 	for author, scores := range authors {
-		as.Authors[i] = map[string]string{
+		if scores["Reviews"].Score < float64(minReviews) {
+			continue
+		}
+		m := map[string]string{
 			"Author": author,
 		}
-		for indexName, score := range scores {
-			as.Authors[i][indexName] = fmt.Sprintf("%d", score)
+		for indexName, s := range scores {
+			m[indexName] = FormatScore(indexName, s.Score)
+			if indexName == "Reviews" {
+				continue
+			}
+			m[indexName+" (mean)"] = FormatScore(indexName, s.Mean)
+			m[indexName+" (95% CI)"] = fmt.Sprintf(
+				"%s – %s",
+				FormatScore(indexName, s.Low),
+				FormatScore(indexName, s.High),
+			)
 		}
-		i++
+		as.Authors = append(as.Authors, m)
 	}
 
-	// Dump the structure to the file
+	// Dump the structure
 	buf, err := json.Marshal(as)
 	if err != nil {
 		return err
 	}
-	_, err = f.Write(buf)
-	if err != nil {
-		return err
-	}
-	return nil
+	_, err = w.Write(buf)
+	return err
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A Phrase is a sequence of words, weighed as a whole.
+type Phrase struct {
+	Text   string
+	Weight int
+	stems  []string
+}
+
+// A PhraseMatcher finds every occurrence of a set of phrases in a stream of
+// words, in one pass, per Aho and Corasick. Words are matched by their
+// stems, so "walls of sound" matches "wall of sound".
+// http://en.wikipedia.org/wiki/Aho%E2%80%93Corasick_string_matching_algorithm
+type PhraseMatcher struct {
+	phrases []Phrase
+	nodes   []phraseNode // the trie of phrases; nodes[0] is the root
+}
+
+type phraseNode struct {
+	next map[string]int // stem -> child
+	fail int            // the node for the longest proper suffix of this one
+	out  []int          // phrases that end here, longest first
+}
+
+// NewPhraseMatcher builds a PhraseMatcher for phrases, keyed by their text,
+// with their weights.
+func NewPhraseMatcher(phrases map[string]int) *PhraseMatcher {
+	m := &PhraseMatcher{nodes: []phraseNode{phraseNode{next: map[string]int{}}}}
+	texts := []string{}
+	for text, _ := range phrases {
+		texts = append(texts, text)
+	}
+	sort.Strings(texts)
+	for _, text := range texts {
+		p := Phrase{Text: text, Weight: phrases[text]}
+		for _, word := range tokenize(text) {
+			p.stems = append(p.stems, Stem(word))
+		}
+		if len(p.stems) <= 0 {
+			continue
+		}
+		m.add(p)
+	}
+	m.link()
+	return m
+}
+
+func (m *PhraseMatcher) add(p Phrase) {
+	n := 0
+	for _, stem := range p.stems {
+		child, ok := m.nodes[n].next[stem]
+		if !ok {
+			child = len(m.nodes)
+			m.nodes = append(m.nodes, phraseNode{next: map[string]int{}})
+			m.nodes[n].next[stem] = child
+		}
+		n = child
+	}
+	m.nodes[n].out = append(m.nodes[n].out, len(m.phrases))
+	m.phrases = append(m.phrases, p)
+}
+
+// link sets the failure links breadth-first, so every node's suffixes are
+// linked before it is.
+func (m *PhraseMatcher) link() {
+	queue := []int{}
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for stem, child := range m.nodes[n].next {
+			f := m.nodes[n].fail
+			for {
+				if next, ok := m.nodes[f].next[stem]; ok {
+					m.nodes[child].fail = next
+					break
+				}
+				if f == 0 {
+					break
+				}
+				f = m.nodes[f].fail
+			}
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[m.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+}
+
+// A PhraseMatch is an occurrence of a phrase in words[Start:End].
+type PhraseMatch struct {
+	Phrase     Phrase
+	Start, End int
+}
+
+// Match returns the phrases in words, leftmost first. Where phrases
+// overlap, the longer one wins, and the other isn't counted: "wears its
+// heart on its sleeve" isn't also "heart on its sleeve".
+func (m *PhraseMatcher) Match(words []string) []PhraseMatch {
+	all, n := []PhraseMatch{}, 0
+	for i, word := range words {
+		stem := Stem(word)
+		for {
+			if next, ok := m.nodes[n].next[stem]; ok {
+				n = next
+				break
+			}
+			if n == 0 {
+				break
+			}
+			n = m.nodes[n].fail
+		}
+		for _, k := range m.nodes[n].out {
+			p := m.phrases[k]
+			all = append(all, PhraseMatch{p, i + 1 - len(p.stems), i + 1})
+		}
+	}
+	sort.Sort(matchesByPosition(all))
+
+	matches, end := []PhraseMatch{}, 0
+	for _, match := range all {
+		if match.Start >= end {
+			matches = append(matches, match)
+			end = match.End
+		}
+	}
+	return matches
+}
+
+type matchesByPosition []PhraseMatch
+
+func (a matchesByPosition) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a matchesByPosition) Len() int      { return len(a) }
+func (a matchesByPosition) Less(i, j int) bool {
+	if a[i].Start != a[j].Start {
+		return a[i].Start < a[j].Start
+	}
+	if a[i].End != a[j].End {
+		return a[i].End > a[j].End
+	}
+	return a[i].Phrase.Text < a[j].Phrase.Text
+}
+
+// LoadPhrases reads phrases from a file, one per line, each preceded by its
+// weight: "5 sonic palette". Blank lines, and lines starting with #, are
+// ignored. The file is read as by DictionaryInfo.
+func LoadPhrases(filename string) (map[string]int, error) {
+	data, _, err := readDictionary(filename)
+	if err != nil {
+		return nil, err
+	}
+	phrases := map[string]int{}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		i := strings.IndexAny(text, " \t")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: expected a weight and a phrase", filename, line)
+		}
+		weight, err := strconv.Atoi(text[:i])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: bad weight %q", filename, line, text[:i])
+		}
+		phrases[strings.ToLower(strings.TrimSpace(text[i:]))] = weight
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return phrases, nil
+}
+
+//
+//
+//
+
+// ClichePhrasesFunc returns an ExplainingFunction that sums the weights of
+// the phrases used in a review.
+func ClichePhrasesFunc(m *PhraseMatcher) ExplainingFunction {
+	return func(r Review) (float64, []Evidence) {
+		tokens := Tokens(r.Body)
+		words := make([]string, len(tokens))
+		for i, tok := range tokens {
+			words[i] = tok.Word
+		}
+		score, evidence := 0, []Evidence{}
+		for _, match := range m.Match(words) {
+			score += match.Phrase.Weight
+			evidence = append(evidence, Evidence{
+				"phrase",
+				tokens[match.Start].Start,
+				tokens[match.End-1].End,
+				match.Phrase.Text,
+				float64(match.Phrase.Weight),
+			})
+		}
+		return float64(score), evidence
+	}
+}
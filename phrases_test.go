@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPhraseMatcher(t *testing.T) {
+	m := NewPhraseMatcher(map[string]int{
+		"wall of sound":                      3,
+		"heart on its sleeve":                4,
+		"wears its heart on its sleeve":      6,
+		"sleeve notes":                       1,
+		"sum of its parts":                   2,
+		"greater than the sum of its parts":  4,
+		"":                                   9, // no words; never matches
+		"the greater than the sum of things": 1,
+	})
+	for _, tuple := range []struct {
+		body     string
+		expected []string
+	}{
+		{"", []string{}},
+		{"no clichés here", []string{}},
+		{"a wall of sound", []string{"wall of sound"}},
+		{"Walls of sound, walls of <em>sound</em>", []string{"wall of sound", "wall of sound"}},
+		{"she wears her heart on its sleeve", []string{"heart on its sleeve"}},
+		{"it's wearing its heart on its sleeve", []string{"wears its heart on its sleeve"}},
+		{"heart on its sleeve notes", []string{"heart on its sleeve"}},
+		{"greater than the sum of its parts", []string{"greater than the sum of its parts"}},
+		{"the greater than the sum of its parts", []string{"greater than the sum of its parts"}},
+		{"less than the sum of its parts", []string{"sum of its parts"}},
+	} {
+		got := []string{}
+		for _, match := range m.Match(tokenize(tuple.body)) {
+			got = append(got, match.Phrase.Text)
+		}
+		if !reflect.DeepEqual(got, tuple.expected) {
+			t.Errorf("%q: got %q, expected %q", tuple.body, got, tuple.expected)
+		}
+	}
+}
+
+func TestClichePhrases(t *testing.T) {
+	e := ClichePhrasesFunc(NewPhraseMatcher(ClichePhrases))
+	r := Review{Body: "<p>A <em>return</em> to form, and a sonic palette that wears its influences on its sleeve.</p>"}
+	score, evidence := e(r)
+	if expected := 4.0 + 5 + 6; score != expected {
+		t.Errorf("got %v, expected %v", score, expected)
+	}
+	if len(evidence) != 3 {
+		t.Fatalf("got %d pieces of evidence, expected 3", len(evidence))
+	}
+	if got := r.Body[evidence[0].Start:evidence[0].End]; got != "return</em> to form" {
+		t.Errorf("got %q, expected the span of the phrase", got)
+	}
+}
+
+func TestLoadPhrases(t *testing.T) {
+	phrases, err := LoadPhrases("testdata/cliches.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"sonic palette": 5, "wall of sound": 3, "sophomore slump": 2}
+	if !reflect.DeepEqual(phrases, expected) {
+		t.Errorf("got %v, expected %v", phrases, expected)
+	}
+
+	f, err := ioutil.TempFile("", "pitchdex-phrases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("3 wall of sound\nsonic palette\n")
+	f.Close()
+	if _, err := LoadPhrases(f.Name()); err == nil {
+		t.Errorf("expected error for a phrase without a weight")
+	}
+
+	idx, _ := LookupIndex("Cliché phrases")
+	if _, err := idx.Build(Params{"phrases": "testdata/nonexistent.txt"}); err == nil {
+		t.Errorf("expected error for a missing phrase file")
+	}
+	f2, err := idx.Build(Params{"phrases": "testdata/cliches.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f2(Review{Body: "a sophomore slump, not a return to form"}); got != 2 {
+		t.Errorf("got %v, expected 2", got)
+	}
+}
@@ -20,8 +20,16 @@ func TestStripHTML(t *testing.T) {
 
 func TestTokenize(t *testing.T) {
 	snippets := map[string][]string{
-		`<p>Easy <em>first</em> one.</p>`: []string{"easy", "first", "one"},
-		`<p><p>Un-matched thing!</p>`:     []string{"un-matched", "thing"},
+		`<p>Easy <em>first</em> one.</p>`:       []string{"easy", "first", "one"},
+		`<p><p>Un-matched thing!</p>`:           []string{"un-matched", "thing"},
+		"Line\nbreaks\tand\u00a0spaces":         []string{"line", "breaks", "and", "spaces"},
+		"Guitars\u2014lots of them\u2013win":    []string{"guitars", "lots", "of", "them", "win"},
+		"\u201cDon\u2019t,\u201d he said":       []string{"don't", "he", "said"},
+		`It&rsquo;s &ldquo;lo&#8209;fi&rdquo;`:  []string{"it's", "lo-fi"},
+		"A 24-track, 7.1 mix of 1,000 songs.":   []string{"a", "24-track", "7.1", "mix", "of", "1,000", "songs"},
+		"rock 'n' roll, the 'band' and 90s-era": []string{"rock", "n", "roll", "the", "band", "and", "90s-era"},
+		"in\u00adcred\u00adible -- trailing- ":  []string{"incredible", "trailing"},
+		`<p></p>`:                               []string{},
 	}
 	for from, expected := range snippets {
 		got := tokenize(from)
@@ -31,6 +39,37 @@ func TestTokenize(t *testing.T) {
 	}
 }
 
+func TestTokenOffsets(t *testing.T) {
+	body := `<p>Its <em>lo&#8209;fi</em> don&rsquo;t-care attitude…</p>`
+	expected := []string{"Its", "lo&#8209;fi", "don&rsquo;t-care", "attitude"}
+	tokens := Tokens(body)
+	if len(tokens) != len(expected) {
+		t.Fatalf("got %v, expected %d tokens", tokens, len(expected))
+	}
+	for i, tok := range tokens {
+		if got := body[tok.Start:tok.End]; got != expected[i] {
+			t.Errorf("%s: offsets [%d:%d] give %q, expected %q", tok.Word, tok.Start, tok.End, got, expected[i])
+		}
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		score    float64
+		expected string
+	}{
+		{"Word length", 4.94, "4.9"},
+		{"Word length", 4, "4.0"},
+		{"Word count", 812.6, "813"},
+		{BullshitScore, 31.26, "31.3"},
+	} {
+		if got := FormatScore(c.name, c.score); got != c.expected {
+			t.Errorf("%s %v: got '%s', expected '%s'", c.name, c.score, got, c.expected)
+		}
+	}
+}
+
 func equal(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Provenance records what a score was computed from: the Version of its
+// index, the InputID of its index, and a Hash of those and everything else
+// it depends on. A score whose Provenance differs from the one it would be
+// computed with now is stale. The zero Provenance is that of scores
+// computed before it was recorded.
+type Provenance struct {
+	Version int
+	Input   string // e.g. "english-1@sha256:…"; "" if the index has none
+	Hash    string
+}
+
+// Digest hashes what scores depend on, like a review's body, once, for
+// NewProvenance.
+func Digest(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewProvenance returns the Provenance of a score by an index of the given
+// version and InputID, of a review whose body has the given Digest. The
+// Hash covers the tokenizer and stemmer too. Composites, which score every
+// review at once, have the Digest of their definition instead.
+func NewProvenance(version int, input, digest string) Provenance {
+	h := sha256.New()
+	fmt.Fprintf(h, "tokenizer %d\nstemmer %d\n%s\n%s", TokenizerVersion, StemmerVersion, input, digest)
+	return Provenance{version, input, hex.EncodeToString(h.Sum(nil))[:16]}
+}
+
+// weightsInfo identifies a weighted word list, such as a lexicon, by name
+// and the hash of its contents, as a DictInfo.
+func weightsInfo(name string, weights map[string]int) DictInfo {
+	lines := []string{}
+	for word, weight := range weights {
+		lines = append(lines, fmt.Sprintf("%d %s\n", weight, word))
+	}
+	sort.Strings(lines)
+	return dictInfo(name, []byte(strings.Join(lines, "")))
+}
+
+// wordsInfo is weightsInfo for a list of words, without weights.
+func wordsInfo(name string, words []string) DictInfo {
+	weights := map[string]int{}
+	for _, word := range words {
+		weights[word] = 1
+	}
+	return weightsInfo(name, weights)
+}
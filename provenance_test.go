@@ -0,0 +1,131 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNewProvenance(t *testing.T) {
+	digest := Digest("<p>Lush.</p>")
+	p := NewProvenance(1, "english-1@sha256:0123", digest)
+	if p != NewProvenance(1, "english-1@sha256:0123", digest) {
+		t.Errorf("expected the same Provenance for the same inputs")
+	}
+	if p.Version != 1 || p.Input != "english-1@sha256:0123" || len(p.Hash) != 16 {
+		t.Errorf("got %+v", p)
+	}
+	for _, other := range []Provenance{
+		NewProvenance(2, "english-1@sha256:0123", digest),
+		NewProvenance(1, "english-2@sha256:4567", digest),
+		NewProvenance(1, "english-1@sha256:0123", Digest("<p>Lush!</p>")),
+	} {
+		if other == p {
+			t.Errorf("%v: expected a different Provenance", other)
+		}
+	}
+}
+
+func TestWeightsInfo(t *testing.T) {
+	a := weightsInfo("lexicon", map[string]int{"lush": 3, "ethereal": 2})
+	b := weightsInfo("lexicon", map[string]int{"ethereal": 2, "lush": 3})
+	c := weightsInfo("lexicon", map[string]int{"ethereal": 2, "lush": 4})
+	if a != b || a == c {
+		t.Errorf("got %v, %v and %v; expected only weights to matter", a, b, c)
+	}
+	if wordsInfo("words", []string{"b", "a"}) != wordsInfo("words", []string{"a", "b", "a"}) {
+		t.Errorf("expected order and duplicates not to matter")
+	}
+}
+
+func TestScoreReviews(t *testing.T) {
+	reviews := Reviews{
+		1: Review{ID: 1, Body: "<p>One two three.</p>"},
+		2: Review{ID: 2, Body: "<p>One two.</p>"},
+	}
+	indexes := IndexMap{"Word count": WordCount}
+	composites := []Composite{{Name: "Verbosity", Weights: map[string]int{"Word count": 1}}}
+	inputs := map[string]string{}
+	for _, tuple := range []struct {
+		name     string
+		change   func()
+		rescore  bool
+		expected ScoringReport
+	}{
+		{"first", func() {}, false, ScoringReport{
+			Computed: map[string]int{"Word count": 2, "Verbosity": 2},
+		}},
+		{"again", func() {}, false, ScoringReport{}},
+		{"body", func() {
+			r := reviews[1]
+			r.Body = "<p>One two three four.</p>"
+			reviews[1] = r
+		}, false, ScoringReport{
+			Computed:   map[string]int{"Word count": 1, "Verbosity": 2},
+			Refreshed:  map[string]int{"Word count": 1},
+			Recomputed: map[string]int{"Verbosity": 2},
+		}},
+		{"input", func() {
+			inputs["Word count"] = "something new"
+		}, false, ScoringReport{
+			Computed:   map[string]int{"Word count": 2, "Verbosity": 2},
+			Refreshed:  map[string]int{"Word count": 2},
+			Recomputed: map[string]int{"Verbosity": 2},
+		}},
+		{"composite", func() {
+			composites[0].Weights["Word count"] = 2
+		}, false, ScoringReport{
+			Computed:  map[string]int{"Verbosity": 2},
+			Refreshed: map[string]int{"Verbosity": 2},
+		}},
+		{"rescore", func() {}, true, ScoringReport{
+			Computed: map[string]int{"Word count": 2, "Verbosity": 2},
+			Forced:   map[string]int{"Word count": 2, "Verbosity": 2},
+		}},
+	} {
+		tuple.change()
+		got := scoreReviews(reviews, indexes, composites, inputs, tuple.rescore)
+		if !equalCounts(got.Computed, tuple.expected.Computed) ||
+			!equalCounts(got.Refreshed, tuple.expected.Refreshed) ||
+			!equalCounts(got.Forced, tuple.expected.Forced) ||
+			!equalCounts(got.Recomputed, tuple.expected.Recomputed) {
+			t.Errorf("%s: got %+v, expected %+v", tuple.name, got, tuple.expected)
+		}
+	}
+	if got := reviews[1].Scores["Word count"]; got != 4 {
+		t.Errorf("got %v, expected the changed body's word count", got)
+	}
+}
+
+func TestChangedInputs(t *testing.T) {
+	reviews := Reviews{
+		1: Review{ID: 1, Body: "<p>One.</p>"},
+		2: Review{ID: 2, Body: "<p>Two.</p>"},
+	}
+	indexes := IndexMap{"Word count": WordCount}
+	scoreReviews(reviews, indexes, nil, map[string]string{"Word count": "english-1@sha256:0123"}, false)
+	r := reviews[2]
+	r.Provenance["Word count"] = Provenance{}
+	reviews[2] = r
+
+	current := map[string]string{"Word count": "words@sha256:4567"}
+	changed := changedInputs(reviews, current)
+	if len(changed) != 1 || len(changed["Word count"]) != 2 ||
+		changed["Word count"]["english-1@sha256:0123"] != 1 || changed["Word count"][""] != 1 {
+		t.Errorf("got %v, expected one score with english-1, and one unrecorded", changed)
+	}
+	scoreReviews(reviews, indexes, nil, current, false)
+	if changed := changedInputs(reviews, current); len(changed) != 0 {
+		t.Errorf("got %v, expected nothing changed after scoring", changed)
+	}
+}
+
+func equalCounts(a, b map[string]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+)
+
+// An AuthorScore is an author's score for one index. Mean is the plain mean
+// of the author's review scores. Score shrinks it toward the mean of every
+// review, the more so the fewer reviews the author has, so that one florid
+// review can't top the rankings. Low and High bound a 95% confidence
+// interval for Mean.
+type AuthorScore struct {
+	Score     float64
+	Mean      float64
+	Low, High float64
+}
+
+// exactly returns an AuthorScore without uncertainty, e.g. a count.
+func exactly(v float64) AuthorScore { return AuthorScore{v, v, v, v} }
+
+// z95 is the two-sided 95% quantile of the standard normal distribution.
+const z95 = 1.959964
+
+// An authorSample accumulates one author's scores for an index, each
+// weighted by the author's share of credit for the review.
+type authorSample struct {
+	n, sum, sumsq float64 // total weight, weighted sum, weighted sum of squares
+}
+
+func (s *authorSample) add(weight, score float64) {
+	s.n += weight
+	s.sum += weight * score
+	s.sumsq += weight * score * score
+}
+
+func (s *authorSample) mean() float64 { return s.sum / s.n }
+
+// shrink estimates every author's score for an index by empirical Bayes,
+// modeling each review's score as the author's true score plus noise.
+// The noise variance is the pooled within-author variance; the variance of
+// true scores between authors is estimated by the method of moments. Each
+// author's mean is shrunk toward the grand mean by the share of its
+// variance that's noise.
+// http://en.wikipedia.org/wiki/Empirical_Bayes_method
+func shrink(samples map[string]*authorSample) map[string]AuthorScore {
+	scores := map[string]AuthorScore{}
+	if len(samples) <= 0 {
+		return scores
+	}
+
+	// Grand mean, and pooled within-author variance
+	n, sum, sumsq, ss := 0.0, 0.0, 0.0, 0.0
+	for _, s := range samples {
+		n += s.n
+		sum += s.sum
+		sumsq += s.sumsq
+		ss += math.Max(0, s.sumsq-s.sum*s.sum/s.n)
+	}
+	grand := sum / n
+	within := math.Max(0, sumsq/n-grand*grand) // too few reviews to pool
+	if df := n - float64(len(samples)); df >= 1 {
+		within = ss / df
+	}
+
+	// Between-author variance: what's left of the variance of author means,
+	// after the noise in each
+	means, noise := []float64{}, 0.0
+	for _, s := range samples {
+		means = append(means, s.mean())
+		noise += within / s.n
+	}
+	between := math.Max(0, Variance(means)-noise/float64(len(samples)))
+
+	for author, s := range samples {
+		b := 0.0 // share of the author's variance that's noise
+		if within > 0 {
+			b = within / (within + s.n*between)
+		}
+		mean, half := s.mean(), z95*math.Sqrt(within/s.n)
+		scores[author] = AuthorScore{
+			Score: b*grand + (1-b)*mean,
+			Mean:  mean,
+			Low:   mean - half,
+			High:  mean + half,
+		}
+	}
+	return scores
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// A reviewer with one florid review mustn't outrank a prolific one who's
+// consistently nearly as florid.
+func TestShrinkageRanksProlificAuthors(t *testing.T) {
+	reviews, id := Reviews{}, 0
+	add := func(author string, scores ...float64) {
+		for _, score := range scores {
+			id++
+			reviews[id] = Review{ID: id, Author: author, Scores: map[string]float64{"Foo": score}}
+		}
+	}
+	// Reviews are noisy; authors differ less than their reviews do.
+	add("One-hit", 90)
+	for i := 0; i < 3; i++ {
+		add("Prolific", 45, 85, 55, 75, 65)
+	}
+	for i := 0; i < 10; i++ {
+		f := float64(i)
+		add(fmt.Sprintf("Staff %d", i), 30+f, 50+f, 70+f, 40+f, 60+f)
+	}
+
+	authors := reviews.AuthorScores([]string{"Foo"}, false)
+	oneHit, prolific := authors["One-hit"]["Foo"], authors["Prolific"]["Foo"]
+	if oneHit.Mean <= prolific.Mean {
+		t.Fatalf("expected One-hit's raw mean %.2f above Prolific's %.2f", oneHit.Mean, prolific.Mean)
+	}
+	if oneHit.Score >= prolific.Score {
+		t.Errorf("One-hit (%.2f) outranks Prolific (%.2f)", oneHit.Score, prolific.Score)
+	}
+	for author, scores := range authors {
+		s := scores["Foo"]
+		if s.Low > s.Mean || s.Mean > s.High {
+			t.Errorf("%s: mean %.2f outside interval %.2f-%.2f", author, s.Mean, s.Low, s.High)
+		}
+	}
+	if oneHit.High-oneHit.Low <= prolific.High-prolific.Low {
+		t.Errorf("expected One-hit's interval to be wider than Prolific's")
+	}
+}
+
+func TestShrinkageDegenerateCases(t *testing.T) {
+	// Every review the same: nothing to shrink, and no uncertainty.
+	same := shrink(map[string]*authorSample{
+		"A": &authorSample{1, 5, 25},
+		"B": &authorSample{2, 10, 50},
+	})
+	for author, s := range same {
+		if s != exactly(5) {
+			t.Errorf("%s: got %+v, expected exactly 5", author, s)
+		}
+	}
+	// One review by one author: no pooled variance to speak of.
+	single := shrink(map[string]*authorSample{"A": &authorSample{1, 7, 49}})
+	if single["A"] != exactly(7) {
+		t.Errorf("got %+v, expected exactly 7", single["A"])
+	}
+}
+
+func TestEncodeAuthorsThreshold(t *testing.T) {
+	authors := map[string]map[string]AuthorScore{
+		"A": {"Reviews": exactly(5), "Word length": AuthorScore{4.9, 5.02, 4.5, 5.54}},
+		"B": {"Reviews": exactly(2), "Word length": exactly(6)},
+	}
+	var buf bytes.Buffer
+	if err := EncodeAuthors(&buf, authors, 3); err != nil {
+		t.Fatalf("%s", err)
+	}
+	var got struct {
+		Authors []map[string]string `json:"aaData"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(got.Authors) != 1 {
+		t.Fatalf("got %v, expected only A", got.Authors)
+	}
+	for k, expected := range map[string]string{
+		"Author":               "A",
+		"Reviews":              "5",
+		"Word length":          "4.9",
+		"Word length (mean)":   "5.0",
+		"Word length (95% CI)": "4.5 – 5.5",
+	} {
+		if got.Authors[0][k] != expected {
+			t.Errorf("%s: got '%s', expected '%s'", k, got.Authors[0][k], expected)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// syllableExceptions are words that the rules in Syllables get wrong.
+var syllableExceptions = map[string]int{
+	"area":       3,
+	"being":      2,
+	"business":   2,
+	"create":     2,
+	"created":    3,
+	"creates":    2,
+	"creating":   3,
+	"creation":   3,
+	"creative":   3,
+	"does":       1,
+	"every":      2,
+	"everything": 3,
+	"fluid":      2,
+	"idea":       3,
+	"ideas":      3,
+	"naked":      2,
+	"people":     2,
+	"poem":       2,
+	"poems":      2,
+	"react":      2,
+	"rhythm":     2,
+	"rhythms":    2,
+	"sacred":     2,
+	"science":    2,
+	"theater":    3,
+	"theatre":    3,
+	"wicked":     2,
+}
+
+// Syllables estimates the number of syllables in a lowercase word, by
+// counting groups of vowels and then correcting for silent e's, vowel pairs
+// that are pronounced apart ("radio"), and the like. Hyphenated words are
+// the sum of their parts. Numbers count as one syllable.
+func Syllables(word string) int {
+	if n, ok := syllableExceptions[word]; ok {
+		return n
+	}
+	if parts := strings.Split(word, "-"); len(parts) > 1 {
+		n := 0
+		for _, part := range parts {
+			n += Syllables(part)
+		}
+		return n
+	}
+
+	w := []rune{}
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			w = append(w, r)
+		}
+	}
+	if len(w) <= 0 {
+		if word != "" {
+			return 1
+		}
+		return 0
+	}
+	s := string(w)
+
+	n := 0
+	for i := range w {
+		if isVowel(w, i) && (i == 0 || !isVowel(w, i-1) || strings.ContainsRune("ëïü", w[i]) || splitsVowels(w, i)) {
+			n++
+		}
+	}
+
+	// Silent e's
+	last := len(w) - 1
+	switch {
+	case len(w) > 2 && w[last] == 'e' && !isVowel(w, last-1) && !consonantLe(w, last-1):
+		n-- // "hope", but not "table"
+	case len(w) > 3 && strings.HasSuffix(s, "es") && !isVowel(w, last-2) &&
+		!strings.ContainsRune("sxzcgh", w[last-2]) && !consonantLe(w, last-2):
+		n-- // "hopes", but not "boxes", "pages" or "tables"
+	case len(w) > 3 && strings.HasSuffix(s, "ed") && !isVowel(w, last-2) &&
+		w[last-2] != 't' && w[last-2] != 'd' && !consonantLe(w, last-2):
+		n-- // "hoped", but not "wanted" or "troubled"
+	}
+	for _, suffix := range []string{"ly", "ful", "less", "ness", "ment"} {
+		// "hopeful", "statement", but not "element"
+		i := len(w) - len(suffix) - 1
+		if strings.HasSuffix(s, suffix) && i >= 2 && w[i] == 'e' && !isVowel(w, i-1) &&
+			isVowel(w, i-2) && !(suffix == "ment" && w[i-2] == 'e') && !consonantLe(w, i-1) {
+			n--
+			break
+		}
+	}
+	if strings.HasSuffix(s, "ism") {
+		n++ // "realism"
+	}
+
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// isVowel reports whether w[i] is a vowel. Y is a vowel except at the start
+// of a word or after another vowel.
+func isVowel(w []rune, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u', 'à', 'á', 'â', 'ä', 'è', 'é', 'ê', 'ë', 'ì', 'í', 'î', 'ï', 'ò', 'ó', 'ô', 'ö', 'ù', 'ú', 'û', 'ü':
+		return true
+	case 'y':
+		return i > 0 && !isVowel(w, i-1)
+	}
+	return false
+}
+
+// consonantLe reports whether w[i] is an l after a consonant, as before the
+// final e of "table": the e is silent, but the l is a syllable of its own.
+func consonantLe(w []rune, i int) bool {
+	return i >= 1 && w[i] == 'l' && !isVowel(w, i-1) && w[i-1] != 'l'
+}
+
+// splitsVowels reports whether the vowels w[i-1] and w[i] are pronounced as
+// two syllables, not one.
+func splitsVowels(w []rune, i int) bool {
+	prev, r := w[i-1], w[i]
+	before := ' '
+	if i >= 2 {
+		before = w[i-2]
+	}
+	rest := string(w[i+1:])
+	switch string([]rune{prev, r}) {
+	case "ia": // "media", but not "special" or "brilliant"
+		return !strings.ContainsRune("ctsg", before) && !(before == 'l' && i >= 3 && w[i-3] == 'l')
+	case "io": // "radio", but not "nation", "region" or "million"
+		return !strings.ContainsRune("ctsgxn", before) && !(before == 'l' && i >= 3 && w[i-3] == 'l')
+	case "iu": // "medium"
+		return true
+	case "eo": // "video", but not "pigeon"
+		return before != 'g'
+	case "ua", "uo": // "actual", but not "quality" or "language"
+		return before != 'q' && before != 'g'
+	case "ie": // "quiet", "happier", but not "field" or "patient"
+		return (rest == "r" || rest == "st" || rest == "t" || rest == "nt") && before != 'c' && before != 't'
+	case "ea": // "idea", "surreal", but not "each" or "deal"
+		return rest == "" || (rest == "l" && before == 'r')
+	case "oe": // "poet", but not "toe"
+		return rest != "" && (rest[0] == 't' || rest[0] == 'm')
+	}
+	return false
+}
+
+//
+//
+//
+
+// textCounts are the counts that readability formulas are built from.
+type textCounts struct {
+	sentences     int
+	words         int
+	syllables     int
+	polysyllables int // words of three or more syllables
+	complex       int // polysyllables, less compounds and those made so by -es, -ed or -ing
+	letters       int
+	characters    int // letters and digits
+}
+
+func countText(r Review) textCounts {
+	c := textCounts{}
+	for _, s := range Sentences(blockText(r.Body)) {
+		words := TextWords(s.Text)
+		if len(words) <= 0 {
+			continue
+		}
+		c.sentences++
+		for _, word := range words {
+			c.words++
+			n := Syllables(word)
+			c.syllables += n
+			if n >= 3 {
+				c.polysyllables++
+				if !strings.Contains(word, "-") && !inflectedToThree(word) {
+					c.complex++
+				}
+			}
+			for _, r := range word {
+				if unicode.IsLetter(r) {
+					c.letters++
+					c.characters++
+				} else if unicode.IsDigit(r) {
+					c.characters++
+				}
+			}
+		}
+	}
+	return c
+}
+
+// inflectedToThree reports whether a word only has three syllables by
+// virtue of an -es, -ed or -ing ending.
+func inflectedToThree(word string) bool {
+	for _, suffix := range []string{"es", "ed", "ing"} {
+		if strings.HasSuffix(word, suffix) && len(word) > len(suffix)+2 {
+			stem := strings.TrimSuffix(word, suffix)
+			if Syllables(stem) < 3 && Syllables(stem+"e") < 3 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c textCounts) wordsPerSentence() float64 {
+	return float64(c.words) / float64(c.sentences)
+}
+
+func (c textCounts) syllablesPerWord() float64 {
+	return float64(c.syllables) / float64(c.words)
+}
+
+// readabilityFunc adapts a readability formula to a ScoringFunction. Reviews
+// without any words score 0.
+func readabilityFunc(formula func(textCounts) float64) ScoringFunction {
+	return func(r Review) float64 {
+		c := countText(r)
+		if c.words <= 0 {
+			return 0
+		}
+		return formula(c)
+	}
+}
+
+// FleschReadingEase is 206.835 - 1.015(words/sentences) -
+// 84.6(syllables/words). Higher is easier; most text scores 0 to 100.
+// http://en.wikipedia.org/wiki/Flesch%E2%80%93Kincaid_readability_tests
+var FleschReadingEase = readabilityFunc(fleschReadingEase)
+
+func fleschReadingEase(c textCounts) float64 {
+	return 206.835 - 1.015*c.wordsPerSentence() - 84.6*c.syllablesPerWord()
+}
+
+// FleschKincaidGrade is 0.39(words/sentences) + 11.8(syllables/words) -
+// 15.59: a U.S. school grade level.
+var FleschKincaidGrade = readabilityFunc(fleschKincaidGrade)
+
+func fleschKincaidGrade(c textCounts) float64 {
+	return 0.39*c.wordsPerSentence() + 11.8*c.syllablesPerWord() - 15.59
+}
+
+// GunningFog is 0.4((words/sentences) + 100(complex words/words)).
+// http://en.wikipedia.org/wiki/Gunning_fog_index
+var GunningFog = readabilityFunc(gunningFog)
+
+func gunningFog(c textCounts) float64 {
+	return 0.4 * (c.wordsPerSentence() + 100*float64(c.complex)/float64(c.words))
+}
+
+// SMOGGrade is 1.0430√(polysyllables × 30/sentences) + 3.1291. It's meant
+// for samples of 30 sentences or more.
+// http://en.wikipedia.org/wiki/SMOG
+var SMOGGrade = readabilityFunc(smogGrade)
+
+func smogGrade(c textCounts) float64 {
+	return 1.0430*math.Sqrt(float64(c.polysyllables)*30/float64(c.sentences)) + 3.1291
+}
+
+// ColemanLiau is 0.0588L - 0.296S - 15.8, where L is letters and S is
+// sentences per 100 words.
+// http://en.wikipedia.org/wiki/Coleman%E2%80%93Liau_index
+var ColemanLiau = readabilityFunc(colemanLiau)
+
+func colemanLiau(c textCounts) float64 {
+	l := 100 * float64(c.letters) / float64(c.words)
+	s := 100 * float64(c.sentences) / float64(c.words)
+	return 0.0588*l - 0.296*s - 15.8
+}
+
+// AutomatedReadability is 4.71(characters/words) + 0.5(words/sentences) -
+// 21.43.
+// http://en.wikipedia.org/wiki/Automated_readability_index
+var AutomatedReadability = readabilityFunc(automatedReadability)
+
+func automatedReadability(c textCounts) float64 {
+	return 4.71*float64(c.characters)/float64(c.words) + 0.5*c.wordsPerSentence() - 21.43
+}
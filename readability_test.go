@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"testing"
+)
+
+func TestSyllables(t *testing.T) {
+	for word, expected := range map[string]int{
+		"":             0,
+		"1999":         1,
+		"the":          1,
+		"a":            1,
+		"cat":          1,
+		"hope":         1,
+		"hopes":        1,
+		"hoped":        1,
+		"wanted":       2,
+		"boxes":        2,
+		"pages":        2,
+		"table":        2,
+		"tables":       2,
+		"troubled":     2,
+		"called":       1,
+		"statement":    2,
+		"element":      3,
+		"hopeful":      2,
+		"lovely":       2,
+		"happier":      3,
+		"quiet":        2,
+		"patient":      2,
+		"radio":        3,
+		"nation":       2,
+		"million":      2,
+		"various":      3,
+		"precious":     2,
+		"video":        3,
+		"pigeon":       2,
+		"actual":       3,
+		"quality":      3,
+		"language":     2,
+		"australian":   4,
+		"reptilian":    4,
+		"creature":     2,
+		"seemingly":    3,
+		"platypus":     3,
+		"ethereal":     4,
+		"hypnotically": 5,
+		"psychedelic":  4,
+		"sophomore":    3,
+		"guitar":       2,
+		"idea":         3,
+		"naïve":        2,
+		"café":         2,
+		"lo-fi":        2,
+		"rhythm":       2,
+	} {
+		if got := Syllables(word); got != expected {
+			t.Errorf("%q: got %d syllables, expected %d", word, got, expected)
+		}
+	}
+}
+
+func TestReadability(t *testing.T) {
+	// The first two are examples from the Flesch-Kincaid article on
+	// Wikipedia, with their published scores. The rest were counted, and
+	// scored from the formulas, by hand.
+	for _, tuple := range []struct {
+		file                         string
+		counts                       textCounts
+		fre, fk, fog, smog, cli, ari float64
+	}{
+		{"cat", textCounts{1, 6, 6, 0, 0, 17, 17}, 116.15, -1.45, 2.40, 3.13, -4.07, -5.09},
+		{"platypus", textCounts{1, 13, 26, 4, 4, 68, 68}, 24.44, 13.08, 17.51, 14.56, 12.68, 9.71},
+		{"review", textCounts{5, 40, 62, 5, 4, 184, 184}, 67.59, 5.82, 7.20, 8.84, 7.55, 4.24},
+		{"plain", textCounts{3, 26, 28, 0, 0, 86, 86}, 106.93, 0.50, 3.47, 3.13, 0.23, -1.52},
+	} {
+		buf, err := ioutil.ReadFile("testdata/readability/" + tuple.file + ".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := Review{Body: string(buf)}
+		if got := countText(r); got != tuple.counts {
+			t.Errorf("%s: got %+v, expected %+v", tuple.file, got, tuple.counts)
+			continue
+		}
+		for _, metric := range []struct {
+			name     string
+			f        ScoringFunction
+			expected float64
+		}{
+			{"Flesch reading ease", FleschReadingEase, tuple.fre},
+			{"Flesch-Kincaid grade", FleschKincaidGrade, tuple.fk},
+			{"Gunning fog", GunningFog, tuple.fog},
+			{"SMOG grade", SMOGGrade, tuple.smog},
+			{"Coleman-Liau index", ColemanLiau, tuple.cli},
+			{"Automated readability index", AutomatedReadability, tuple.ari},
+		} {
+			if got := metric.f(r); math.Abs(got-metric.expected) > 0.01 {
+				t.Errorf("%s: %s: got %.3f, expected %.2f", tuple.file, metric.name, got, metric.expected)
+			}
+		}
+	}
+
+	// Scores from another implementation, jdkato/prose v1.2.1, in its
+	// testdata/summarize.json, for a text that it counts as we do. Its
+	// Coleman-Liau index counts letters and digits, so that formula is
+	// checked against its counts for a text without digits.
+	buf, err := ioutil.ReadFile("testdata/readability/engineer.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := Review{Body: string(buf)}
+	if got, expected := countText(r), (textCounts{1, 16, 28, 3, 3, 74, 75}); got != expected {
+		t.Errorf("engineer: got %+v, expected %+v", got, expected)
+	}
+	for _, metric := range []struct {
+		name     string
+		f        ScoringFunction
+		expected float64
+	}{
+		{"Gunning fog", GunningFog, 13.90},
+		{"SMOG grade", SMOGGrade, 13.02},
+		{"Automated readability index", AutomatedReadability, 8.65},
+	} {
+		if got := metric.f(r); math.Abs(got-metric.expected) > 0.005 {
+			t.Errorf("engineer: %s: got %.3f, expected %.2f", metric.name, got, metric.expected)
+		}
+	}
+	if got := colemanLiau(textCounts{sentences: 2, words: 43, letters: 222}); math.Abs(got-13.18) > 0.005 {
+		t.Errorf("Coleman-Liau index: got %.3f, expected 13.18", got)
+	}
+
+	for _, f := range []ScoringFunction{FleschReadingEase, GunningFog, SMOGGrade} {
+		if got := f(Review{Body: "<p></p>"}); got != 0 {
+			t.Errorf("empty review: got %v, expected 0", got)
+		}
+	}
+}
+
+func TestCountTextEscapes(t *testing.T) {
+	r := Review{Body: "<p>Is 1 &lt; 2 and b &lt;c or d?</p>"}
+	if got := countText(r); got.words != 8 || got.sentences != 1 {
+		t.Errorf("got %+v, expected 8 words in 1 sentence", got)
+	}
+}
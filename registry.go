@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Direction describes how an index relates to Bullshit.
+type Direction int
+
+const (
+	Neutral     Direction = iota // informational only
+	MoreIsWorse                  // higher scores mean more Bullshit
+	LessIsWorse                  // lower scores mean more Bullshit
+)
+
+func (d Direction) String() string {
+	switch d {
+	case MoreIsWorse:
+		return "more-is-worse"
+	case LessIsWorse:
+		return "less-is-worse"
+	}
+	return "neutral"
+}
+
+// Params are the configurable parameters of an index, by name.
+type Params map[string]string
+
+// IndexFactory builds a ScoringFunction from a complete set of Params.
+type IndexFactory func(Params) (ScoringFunction, error)
+
+// ExplainerFactory builds an ExplainingFunction from a complete set of
+// Params.
+type ExplainerFactory func(Params) (ExplainingFunction, error)
+
+// InputFunc identifies the data, besides the review, that an index's scores
+// depend on, such as a lexicon or a dictionary, from a complete set of
+// Params.
+type InputFunc func(Params) (string, error)
+
+// An Index is a registered scoring index and its metadata. It needs New, or
+// Explain if it can give evidence for its scores. Indexes that depend on
+// data, such as a lexicon or a dictionary, should have an Input, so that
+// scores computed with other data are rescored.
+type Index struct {
+	Name        string
+	Description string
+	Direction   Direction
+	Version     int
+	Precision   int    // decimal places to display
+	Params      Params // accepted parameters -> default values
+	New         IndexFactory
+	Explain     ExplainerFactory
+	Input       InputFunc // optional
+}
+
+// params validates the given Params against the ones the Index accepts, and
+// fills in defaults.
+func (idx Index) params(p Params) (Params, error) {
+	merged := Params{}
+	for k, v := range idx.Params {
+		merged[k] = v
+	}
+	for k, v := range p {
+		if _, ok := idx.Params[k]; !ok {
+			return nil, fmt.Errorf("index %q: unknown parameter %q", idx.Name, k)
+		}
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// Build validates the given Params against the ones the Index accepts,
+// fills in defaults, and returns the resulting ScoringFunction.
+func (idx Index) Build(p Params) (ScoringFunction, error) {
+	if idx.New == nil {
+		e, err := idx.BuildExplainer(p)
+		if err != nil {
+			return nil, err
+		}
+		return e.ScoringFunction(), nil
+	}
+	merged, err := idx.params(p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := idx.New(merged)
+	if err != nil {
+		return nil, fmt.Errorf("index %q: %s", idx.Name, err)
+	}
+	return f, nil
+}
+
+// BuildExplainer is like Build, but returns an ExplainingFunction. Indexes
+// without an Explain give no evidence.
+func (idx Index) BuildExplainer(p Params) (ExplainingFunction, error) {
+	if idx.Explain == nil {
+		f, err := idx.Build(p)
+		if err != nil {
+			return nil, err
+		}
+		return Unexplained(f), nil
+	}
+	merged, err := idx.params(p)
+	if err != nil {
+		return nil, err
+	}
+	e, err := idx.Explain(merged)
+	if err != nil {
+		return nil, fmt.Errorf("index %q: %s", idx.Name, err)
+	}
+	return e, nil
+}
+
+// InputID returns the identity of the data the Index's scores depend on,
+// given its Params; "" if they only depend on the review.
+func (idx Index) InputID(p Params) (string, error) {
+	if idx.Input == nil {
+		return "", nil
+	}
+	merged, err := idx.params(p)
+	if err != nil {
+		return "", err
+	}
+	id, err := idx.Input(merged)
+	if err != nil {
+		return "", fmt.Errorf("index %q: %s", idx.Name, err)
+	}
+	return id, nil
+}
+
+var registry = map[string]Index{}
+
+// RegisterIndex makes an Index available to the configuration. It's meant
+// to be called from init functions, and panics on programmer error.
+func RegisterIndex(idx Index) {
+	if idx.Name == "" || (idx.New == nil && idx.Explain == nil) {
+		panic("RegisterIndex: index needs a Name, and a New or Explain function")
+	}
+	if _, ok := registry[idx.Name]; ok {
+		panic(fmt.Sprintf("RegisterIndex: %q registered twice", idx.Name))
+	}
+	registry[idx.Name] = idx
+}
+
+// defaultPrecision is the display precision of scores that aren't from a
+// registered index, i.e. composites.
+const defaultPrecision = 1
+
+// FormatScore formats a score for display, to the precision of its index.
+func FormatScore(name string, score float64) string {
+	precision := defaultPrecision
+	if idx, ok := LookupIndex(name); ok {
+		precision = idx.Precision
+	}
+	return strconv.FormatFloat(score, 'f', precision, 64)
+}
+
+func LookupIndex(name string) (Index, bool) {
+	idx, ok := registry[name]
+	return idx, ok
+}
+
+// RegisteredIndexes returns every registered Index, sorted by name.
+func RegisteredIndexes() []Index {
+	indexes := make([]Index, 0, len(registry))
+	for _, idx := range registry {
+		indexes = append(indexes, idx)
+	}
+	sort.Sort(indexesByName(indexes))
+	return indexes
+}
+
+func registeredNames() string {
+	names := []string{}
+	for _, idx := range RegisteredIndexes() {
+		names = append(names, fmt.Sprintf("%q", idx.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+type indexesByName []Index
+
+func (a indexesByName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a indexesByName) Len() int           { return len(a) }
+func (a indexesByName) Less(i, j int) bool { return a[i].Name < a[j].Name }
+
+// Static adapts a parameterless ScoringFunction to an IndexFactory.
+func Static(f ScoringFunction) IndexFactory {
+	return func(Params) (ScoringFunction, error) { return f, nil }
+}
+
+// StaticExplainer adapts a parameterless ExplainingFunction to an
+// ExplainerFactory.
+func StaticExplainer(e ExplainingFunction) ExplainerFactory {
+	return func(Params) (ExplainingFunction, error) { return e, nil }
+}
@@ -2,18 +2,245 @@ package main
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/peterbourgon/exp-html"
 	"strings"
+	"unicode"
 )
 
-var IndexDefinitions = IndexMap{
-	"Reviews":               SimpleCount,
-	"Pitchformulaity":       Pitchformulaity,
-	"Naïve sentence length": NaïveSentenceLength,
-	"Words invented":        InventedWordsFunc(*dictFile),
-	"Character count":       CharacterCount,
-	"Word count":            WordCount,
-	"Word length":           AverageWordLength,
+// IndexDefinitions holds the active indexes. It's built from the Config at
+// startup.
+var IndexDefinitions = IndexMap{}
+
+func init() {
+	RegisterIndex(Index{
+		Name:        "Reviews",
+		Description: "Number of reviews; always 1 per review.",
+		Direction:   Neutral,
+		Version:     1,
+		New:         Static(SimpleCount),
+	})
+	RegisterIndex(Index{
+		Name:        "Pitchformulaity",
+		Description: "Sum of the triteness of every Pitchformula word used, in any form.",
+		Direction:   MoreIsWorse,
+		Version:     2,
+		Precision:   1,
+		Explain:     StaticExplainer(ExplainPitchformulaity),
+		Input: func(Params) (string, error) {
+			words := weightsInfo("pitchformula-words", PitchformulaWords)
+			forms := weightsInfo("pitchformula-forms", PitchformulaForms)
+			return words.String() + " + " + forms.String(), nil
+		},
+	})
+	RegisterIndex(Index{
+		Name:        "Cliché phrases",
+		Description: "Sum of the triteness of every cliché phrase used.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		Params:      Params{"phrases": ""}, // empty means ClichePhrases
+		Explain: func(p Params) (ExplainingFunction, error) {
+			phrases := ClichePhrases
+			if p["phrases"] != "" {
+				var err error
+				if phrases, err = LoadPhrases(p["phrases"]); err != nil {
+					return nil, err
+				}
+			}
+			return ClichePhrasesFunc(NewPhraseMatcher(phrases)), nil
+		},
+		Input: func(p Params) (string, error) {
+			if p["phrases"] == "" {
+				return weightsInfo("cliche-phrases", ClichePhrases).String(), nil
+			}
+			info, err := DictionaryInfo(p["phrases"])
+			return info.String(), err
+		},
+	})
+	RegisterIndex(Index{
+		Name:        "Naïve sentence length",
+		Description: "Words per period. Superseded by Sentence length.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(NaïveSentenceLength),
+	})
+	RegisterIndex(Index{
+		Name:        "Sentence length",
+		Description: "Mean words per sentence.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		Explain:     StaticExplainer(ExplainMeanSentenceLength),
+	})
+	RegisterIndex(Index{
+		Name:        "Sentence length (median)",
+		Description: "Median words per sentence.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		Explain:     StaticExplainer(ExplainMedianSentenceLength),
+	})
+	RegisterIndex(Index{
+		Name:        "Sentence length (max)",
+		Description: "Words in the longest sentence.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   0,
+		Explain:     StaticExplainer(ExplainMaxSentenceLength),
+	})
+	RegisterIndex(Index{
+		Name:        "Words invented",
+		Description: "Number of words not found in the dictionary, in any form.",
+		Direction:   MoreIsWorse,
+		Version:     2,
+		Precision:   1,
+		Params: Params{
+			"dict":      "", // empty means the -dict flag, or the built-in word list
+			"whitelist": "", // more words to allow, besides GenreWords
+		},
+		Explain: func(p Params) (ExplainingFunction, error) {
+			dict, _, err := LoadDictionary(dictionaryParam(p))
+			if err != nil {
+				return nil, fmt.Errorf("%s (set -dict, or make the index inactive)", err)
+			}
+			for _, word := range GenreWords {
+				dict[word] = struct{}{}
+			}
+			if p["whitelist"] != "" {
+				if _, err := dict.LoadDictionary(p["whitelist"]); err != nil {
+					return nil, err
+				}
+			}
+			return ExplainInventedWordsFunc(dict), nil
+		},
+		Input: func(p Params) (string, error) {
+			info, err := DictionaryInfo(dictionaryParam(p))
+			if err != nil {
+				return "", err
+			}
+			id := info.String() + " + " + wordsInfo("genre-words", GenreWords).String()
+			if p["whitelist"] != "" {
+				whitelist, err := DictionaryInfo(p["whitelist"])
+				if err != nil {
+					return "", err
+				}
+				id += " + " + whitelist.String()
+			}
+			return id, nil
+		},
+	})
+	RegisterIndex(Index{
+		Name:        "Flesch reading ease",
+		Description: "Flesch Reading Ease: higher is easier to read.",
+		Direction:   LessIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(FleschReadingEase),
+	})
+	RegisterIndex(Index{
+		Name:        "Flesch-Kincaid grade",
+		Description: "Flesch-Kincaid Grade Level.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(FleschKincaidGrade),
+	})
+	RegisterIndex(Index{
+		Name:        "Gunning fog",
+		Description: "Gunning fog index: years of schooling needed to read it.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(GunningFog),
+	})
+	RegisterIndex(Index{
+		Name:        "SMOG grade",
+		Description: "SMOG grade: years of schooling needed to read it.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(SMOGGrade),
+	})
+	RegisterIndex(Index{
+		Name:        "Coleman-Liau index",
+		Description: "Coleman-Liau index: a grade level, from letters per word.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(ColemanLiau),
+	})
+	RegisterIndex(Index{
+		Name:        "Automated readability index",
+		Description: "Automated Readability Index: a grade level, from characters per word.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(AutomatedReadability),
+	})
+	RegisterIndex(Index{
+		Name:        "Type-token ratio",
+		Description: "Distinct words per word. Falls as reviews get longer.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   3,
+		New:         Static(diversityFunc(TypeTokenRatio)),
+	})
+	RegisterIndex(Index{
+		Name:        "MTLD",
+		Description: "Measure of textual lexical diversity: mean words per run of 0.72 type-token ratio.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(diversityFunc(MTLD)),
+	})
+	RegisterIndex(Index{
+		Name:        "HD-D",
+		Description: "Expected type-token ratio of 42 words drawn at random.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   3,
+		New:         Static(diversityFunc(HDD)),
+	})
+	RegisterIndex(Index{
+		Name:        "Yule's K",
+		Description: "Yule's characteristic of vocabulary repetition; lower is more diverse.",
+		Direction:   LessIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(diversityFunc(YulesK)),
+	})
+	RegisterIndex(Index{
+		Name:        "Hapax ratio",
+		Description: "Share of distinct words used only once.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   3,
+		New:         Static(diversityFunc(HapaxRatio)),
+	})
+	RegisterIndex(Index{
+		Name:        "Character count",
+		Description: "Number of characters, excluding markup.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		New:         Static(CharacterCount),
+	})
+	RegisterIndex(Index{
+		Name:        "Word count",
+		Description: "Number of words.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		New:         Static(WordCount),
+	})
+	RegisterIndex(Index{
+		Name:        "Word length",
+		Description: "Average characters per word.",
+		Direction:   MoreIsWorse,
+		Version:     1,
+		Precision:   1,
+		New:         Static(AverageWordLength),
+	})
 }
 
 const BullshitScore = "Overall Bullshit Score"
@@ -22,35 +249,55 @@ const BullshitScore = "Overall Bullshit Score"
 //
 //
 
-func SimpleCount(r Review) int { return 1 }
+func SimpleCount(r Review) float64 { return 1 }
 
-func WordCount(r Review) int {
-	return len(tokenize(r.Body))
+func WordCount(r Review) float64 {
+	return float64(len(tokenize(r.Body)))
 }
 
-func CharacterCount(r Review) int {
-	return len(stripHTML(r.Body))
+func CharacterCount(r Review) float64 {
+	return float64(len(stripHTML(r.Body)))
 }
 
-func AverageWordLength(r Review) int {
-	return int(float64(CharacterCount(r)) / float64(WordCount(r)))
+func AverageWordLength(r Review) float64 {
+	if WordCount(r) <= 0 {
+		return 0
+	}
+	return CharacterCount(r) / WordCount(r)
 }
 
-func InventedWordsFunc(dictfile string) func(r Review) int {
-	dict := NewDict(dictfile)
-	return func(r Review) int {
-		count := 0
-		for _, word := range tokenize(r.Body) {
-			if !dict.Has(word) {
-				// fmt.Printf("invented '%s'\n", baseWord(word))
-				count++
+// dictionaryParam is the dictionary file the Words invented index reads:
+// its dict parameter, else the -dict flag, else the BuiltinDictionary.
+func dictionaryParam(p Params) string {
+	if p["dict"] != "" {
+		return p["dict"]
+	}
+	return *dictFile
+}
+
+func InventedWordsFunc(dict Dict) ScoringFunction {
+	return ExplainInventedWordsFunc(dict).ScoringFunction()
+}
+
+// ExplainInventedWordsFunc counts the words that aren't in dict in any form.
+// Numbers, and names (capitalized words that don't begin a sentence), don't
+// count.
+func ExplainInventedWordsFunc(dict Dict) ExplainingFunction {
+	return func(r Review) (float64, []Evidence) {
+		tokens := Tokens(r.Body)
+		names := properNouns(r.Body, tokens)
+		evidence := []Evidence{}
+		for _, tok := range tokens {
+			if names[tok.Start] || strings.IndexFunc(tok.Word, unicode.IsDigit) >= 0 || dict.Known(tok.Word) {
+				continue
 			}
+			evidence = append(evidence, Evidence{"invented", tok.Start, tok.End, tok.Word, 1})
 		}
-		return count
+		return float64(len(evidence)), evidence
 	}
 }
 
-func NaïveSentenceLength(r Review) int {
+func NaïveSentenceLength(r Review) float64 {
 	i, sentences, b := 0, 0, stripHTML(r.Body)
 	for {
 		j := strings.Index(b[i:], ".")
@@ -60,35 +307,41 @@ func NaïveSentenceLength(r Review) int {
 		sentences++
 		i = i + j + 1
 	}
-	return int(float64(WordCount(r)) / float64(sentences))
+	if sentences <= 0 {
+		sentences = 1
+	}
+	return WordCount(r) / float64(sentences)
+}
+
+func Pitchformulaity(r Review) float64 {
+	score, _ := ExplainPitchformulaity(r)
+	return score
 }
 
-func Pitchformulaity(r Review) int {
-	score := 0
-	for _, word := range tokenize(r.Body) {
-		if n, ok := PitchformulaWords[word]; ok {
+func ExplainPitchformulaity(r Review) (float64, []Evidence) {
+	score, evidence := 0, []Evidence{}
+	for _, tok := range Tokens(r.Body) {
+		if n, ok := PitchformulaLexicon.Weight(tok.Word); ok {
 			score += n
+			evidence = append(evidence, Evidence{"term", tok.Start, tok.End, tok.Word, float64(n)})
 		}
 	}
-	return score
+	return float64(score), evidence
 }
 
 //
 //
 //
 
+// tokenize returns the words of body, per Tokens.
 func tokenize(body string) []string {
-	toks := strings.Split(stripHTML(body), " ")
-	for i, tok := range toks {
-		toks[i] = baseWord(tok)
+	toks := []string{}
+	for _, tok := range Tokens(body) {
+		toks = append(toks, tok.Word)
 	}
 	return toks
 }
 
-func baseWord(word string) string {
-	return strings.ToLower(strings.Trim(word, ` ,.;!?"-`))
-}
-
 func stripHTML(s string) string {
 	z := html.NewTokenizer(bytes.NewBufferString(s))
 	results := []string{}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"github.com/peterbourgon/exp-html"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Sentence is a span of the text it was split from, by byte offsets.
+type Sentence struct {
+	Start, End int
+	Text       string
+}
+
+// Abbreviations whose period never ends a sentence, lowercased and without
+// their final period. Tuned for music writing.
+var abbreviations = map[string]bool{
+	// titles and names
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true,
+	"st": true, "mt": true, "jr": true, "sr": true, "rev": true,
+	"messrs": true, "mme": true, "mlle": true,
+	// credits and catalog
+	"feat": true, "ft": true, "vs": true, "prod": true, "arr": true,
+	"orig": true, "cf": true, "approx": true, "ca": true, "incl": true,
+	"e.g": true, "i.e": true, "al": true,
+	// business
+	"bros": true, "inc": true, "co": true, "corp": true, "ltd": true,
+	"dept": true, "assn": true,
+	// places and times
+	"u.s": true, "u.k": true, "l.a": true, "n.y": true, "d.c": true,
+	"a.m": true, "p.m": true, "ave": true, "blvd": true,
+}
+
+// Abbreviations whose period never ends a sentence when a number follows,
+// e.g. "Vol. 2", "No. 1", "Op. 27".
+var numberAbbreviations = map[string]bool{
+	"no": true, "nos": true, "vol": true, "vols": true, "pt": true,
+	"op": true, "p": true, "pp": true, "ch": true, "min": true,
+}
+
+// blockTags end a sentence, whether or not it ended with punctuation.
+var blockTags = map[string]bool{
+	"p": true, "br": true, "div": true, "li": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// blockText strips HTML from s, like stripHTML, but puts a newline at every
+// block-level tag so paragraphs don't run together.
+func blockText(s string) string {
+	text, _ := blockTextSpans(s)
+	return text
+}
+
+// blockTextSpans is blockText, but also returns, for each byte of the text,
+// the span of s it was decoded from.
+func blockTextSpans(s string) (string, []char) {
+	z := html.NewTokenizer(bytes.NewBufferString(s))
+	var buf bytes.Buffer
+	spans, offset := []char{}, 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return buf.String(), spans
+		}
+		raw := z.Raw()
+		n := len(raw)
+		switch tt {
+		case html.TextToken:
+			for _, c := range decodeText(string(raw), offset) {
+				size, _ := buf.WriteRune(c.r)
+				for i := 0; i < size; i++ {
+					spans = append(spans, c)
+				}
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			if name, _ := z.TagName(); blockTags[string(name)] {
+				buf.WriteByte('\n')
+				spans = append(spans, char{'\n', offset, offset + n})
+			}
+		}
+		offset += n
+	}
+}
+
+func isTerminator(r rune) bool {
+	return r == '.' || r == '!' || r == '?' || r == '…'
+}
+
+func isCloser(r rune) bool {
+	return strings.ContainsRune(`"')]}”’»`, r)
+}
+
+// Sentences splits plain text into sentences. A sentence ends at a line
+// break, or at terminal punctuation followed by whitespace and something
+// other than a lowercase letter, unless the punctuation is the period of
+// an abbreviation or initial.
+func Sentences(text string) []Sentence {
+	sentences := []Sentence{}
+	start := -1 // -1 between sentences
+	end := func(i int) {
+		if start >= 0 {
+			t := strings.TrimRightFunc(text[start:i], unicode.IsSpace)
+			sentences = append(sentences, Sentence{start, start + len(t), t})
+		}
+		start = -1
+	}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\n':
+			end(i)
+		case start < 0 && !unicode.IsSpace(r):
+			start = i
+		}
+		if start >= 0 && isTerminator(r) {
+			j := i
+			for j < len(text) {
+				r2, size2 := utf8.DecodeRuneInString(text[j:])
+				if !isTerminator(r2) && !isCloser(r2) {
+					break
+				}
+				j += size2
+			}
+			if isBoundary(text, start, i, j) {
+				end(j)
+			}
+			i = j
+			continue
+		}
+		i += size
+	}
+	end(len(text))
+	return sentences
+}
+
+// isBoundary decides whether the run of terminal punctuation and closers
+// text[i:j] ends the sentence begun at start.
+func isBoundary(text string, start, i, j int) bool {
+	if j >= len(text) {
+		return true
+	}
+	next, _ := utf8.DecodeRuneInString(text[j:])
+	if !unicode.IsSpace(next) {
+		return false // "3.5", "LP.s", "Yahoo!'s"
+	}
+	rest := strings.TrimLeftFunc(text[j:], func(r rune) bool {
+		return unicode.IsSpace(r) && r != '\n'
+	})
+	if rest == "" || rest[0] == '\n' {
+		return true
+	}
+	following, _ := utf8.DecodeRuneInString(rest)
+	if unicode.IsLower(following) {
+		return false // "... and then", "etc. and so on"
+	}
+	if text[i:j] != "." {
+		return true
+	}
+
+	// A lone period: is it an abbreviation's?
+	word := text[start:i]
+	if k := strings.LastIndexFunc(word, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"'(“‘[`, r)
+	}); k >= 0 {
+		_, size := utf8.DecodeRuneInString(word[k:])
+		word = word[k+size:]
+	}
+	lower := strings.ToLower(word)
+	if abbreviations[lower] {
+		return false
+	}
+	if numberAbbreviations[lower] && unicode.IsDigit(following) {
+		return false
+	}
+	if r, size := utf8.DecodeRuneInString(word); size == len(word) && unicode.IsUpper(r) {
+		return false // an initial, as in "J. Mascis"
+	}
+	return true
+}
+
+// explainSentences returns the number of words in each sentence of r, and
+// each sentence as Evidence, weighted by its length.
+func explainSentences(r Review) ([]int, []Evidence) {
+	text, spans := blockTextSpans(r.Body)
+	lengths, evidence := []int{}, []Evidence{}
+	for _, s := range Sentences(text) {
+		if n := len(TextWords(s.Text)); n > 0 {
+			lengths = append(lengths, n)
+			evidence = append(evidence, Evidence{
+				Kind:   "sentence",
+				Start:  spans[s.Start].start,
+				End:    spans[s.End-1].end,
+				Text:   s.Text,
+				Weight: float64(n),
+			})
+		}
+	}
+	return lengths, evidence
+}
+
+// sentenceExplainer adapts a statistic over sentence lengths to an
+// ExplainingFunction.
+func sentenceExplainer(f func(lengths []int) float64) ExplainingFunction {
+	return func(r Review) (float64, []Evidence) {
+		lengths, evidence := explainSentences(r)
+		if len(lengths) <= 0 {
+			return 0, evidence
+		}
+		return f(lengths), evidence
+	}
+}
+
+var (
+	ExplainMeanSentenceLength = sentenceExplainer(func(lengths []int) float64 {
+		total := 0
+		for _, n := range lengths {
+			total += n
+		}
+		return float64(total) / float64(len(lengths))
+	})
+	ExplainMedianSentenceLength = sentenceExplainer(func(lengths []int) float64 {
+		sort.Ints(lengths)
+		if len(lengths)%2 == 1 {
+			return float64(lengths[len(lengths)/2])
+		}
+		return float64(lengths[len(lengths)/2-1]+lengths[len(lengths)/2]) / 2
+	})
+	ExplainMaxSentenceLength = sentenceExplainer(func(lengths []int) float64 {
+		max := 0
+		for _, n := range lengths {
+			if n > max {
+				max = n
+			}
+		}
+		return float64(max)
+	})
+
+	MeanSentenceLength   = ExplainMeanSentenceLength.ScoringFunction()
+	MedianSentenceLength = ExplainMedianSentenceLength.ScoringFunction()
+	MaxSentenceLength    = ExplainMaxSentenceLength.ScoringFunction()
+)
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// Each testdata/sentences/*.txt excerpt is segmented and compared with its
+// .golden file, one sentence per line. Run with -update to regenerate them.
+func TestSentencesGolden(t *testing.T) {
+	inputs, err := filepath.Glob("testdata/sentences/*.txt")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(inputs) <= 0 {
+		t.Fatalf("no test corpus")
+	}
+	for _, input := range inputs {
+		buf, err := ioutil.ReadFile(input)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		texts := []string{}
+		for _, s := range Sentences(blockText(string(buf))) {
+			texts = append(texts, s.Text)
+		}
+		got := strings.Join(texts, "\n") + "\n"
+
+		golden := strings.TrimSuffix(input, ".txt") + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatalf("%s", err)
+			}
+			continue
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if got != string(expected) {
+			t.Errorf("%s: got\n%s\nexpected\n%s", input, got, expected)
+		}
+	}
+}
+
+func TestSentenceOffsets(t *testing.T) {
+	text := "  One here.  Two there!\nThree"
+	for _, s := range Sentences(text) {
+		if text[s.Start:s.End] != s.Text {
+			t.Errorf("%q: offsets [%d:%d] give %q", s.Text, s.Start, s.End, text[s.Start:s.End])
+		}
+	}
+}
+
+func TestSentenceLength(t *testing.T) {
+	r := Review{Body: "<p>One two three. One two three four five six seven. One two three four five six? One.</p>"}
+	for _, c := range []struct {
+		name     string
+		f        ScoringFunction
+		expected float64
+	}{
+		{"mean", MeanSentenceLength, 4.25},
+		{"median", MedianSentenceLength, 4.5},
+		{"max", MaxSentenceLength, 7},
+	} {
+		if got := c.f(r); got != c.expected {
+			t.Errorf("%s: got %.2f, expected %.2f", c.name, got, c.expected)
+		}
+	}
+	empty := Review{Body: "<p></p>"}
+	for _, f := range []ScoringFunction{MeanSentenceLength, MedianSentenceLength, MaxSentenceLength} {
+		if got := f(empty); got != 0 {
+			t.Errorf("empty review: got %.2f, expected 0", got)
+		}
+	}
+}
+
+func TestSentenceLengthEscapes(t *testing.T) {
+	// Decoded, "&lt;" is a "<", which mustn't be taken for the start of a tag
+	r := Review{Body: "<p>Is 1 &lt; 2 and b &lt;c or d? Yes, a &amp; b.</p>"}
+	if got := MaxSentenceLength(r); got != 8 {
+		t.Errorf("got %.2f, expected 8", got)
+	}
+	if got := TextWords("a <b> c & d"); len(got) != 4 {
+		t.Errorf("got %q, expected 4 words", got)
+	}
+}
@@ -1,53 +1,54 @@
 package main
 
 import (
-	"log"
 	"math"
+	"sort"
 )
 
+// StatisticalData summarizes the distribution of an index's scores. Reviews
+// without a score for the index are left out, not counted as 0.
 type StatisticalData struct {
 	IndexName         string
 	Instances         int
-	Minimum           int
-	Mean              int
-	Maximum           int
-	StandardDeviation int
+	Minimum           float64
+	Mean              float64
+	Maximum           float64
+	Variance          float64 // population variance
+	StandardDeviation float64
+	Median            float64
+	MAD               float64   // median absolute deviation from the median
+	P25, P75          float64   // quartiles
+	Values            []float64 // sorted
 }
 
-func Gather(reviews Reviews, indexName string) StatisticalData {
-	first, min, total, max, count := true, 0, 0, 0, 0
+// Values returns every score for the named index, sorted.
+func Values(reviews Reviews, indexName string) []float64 {
+	values := []float64{}
 	for _, review := range reviews {
 		if score, ok := review.Scores[indexName]; ok {
-			if first || score < min {
-				min = score
-				first = false
-			}
-			if first || score > max {
-				max = score
-				first = false
-			}
-			total += score
-			count++
+			values = append(values, score)
 		}
 	}
-	mean := int(float64(total) / float64(count))
-
-	// http://en.wikipedia.org/wiki/Standard_deviation
-	sqdev := 0.0
-	for _, review := range reviews {
-		x := review.Scores[indexName] - mean
-		sqdev += math.Pow(float64(x), 2)
-	}
-	stdev := int(math.Sqrt(sqdev / float64(count)))
+	sort.Float64s(values)
+	return values
+}
 
-	return StatisticalData{
-		IndexName:         indexName,
-		Instances:         count,
-		Minimum:           min,
-		Mean:              mean,
-		Maximum:           max,
-		StandardDeviation: stdev,
+func Gather(reviews Reviews, indexName string) StatisticalData {
+	values := Values(reviews, indexName)
+	stats := StatisticalData{IndexName: indexName, Instances: len(values), Values: values}
+	if len(values) <= 0 {
+		return stats
 	}
+	stats.Minimum = values[0]
+	stats.Maximum = values[len(values)-1]
+	stats.Mean = Mean(values)
+	stats.Variance = Variance(values)
+	stats.StandardDeviation = math.Sqrt(stats.Variance)
+	stats.Median = Median(values)
+	stats.MAD = MAD(values)
+	stats.P25 = Percentile(values, 25)
+	stats.P75 = Percentile(values, 75)
+	return stats
 }
 
 type AllStatisticalData map[string]StatisticalData
@@ -60,17 +61,99 @@ func GatherAll(reviews Reviews) AllStatisticalData {
 	return allStats
 }
 
-func DeviationsFromMinimum(score int, stats StatisticalData) int {
-	for i := 1; i <= 10; i++ {
-		if score <= stats.Minimum+(i*stats.StandardDeviation) {
-			return i
+//
+//
+//
+
+// Mean returns the arithmetic mean of values, or 0 if there are none.
+func Mean(values []float64) float64 {
+	if len(values) <= 0 {
+		return 0
+	}
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// Variance returns the population variance of values.
+// http://en.wikipedia.org/wiki/Variance
+func Variance(values []float64) float64 {
+	if len(values) <= 0 {
+		return 0
+	}
+	mean, sqdev := Mean(values), 0.0
+	for _, v := range values {
+		sqdev += (v - mean) * (v - mean)
+	}
+	return sqdev / float64(len(values))
+}
+
+// Percentile returns the pth percentile (0-100) of sorted values,
+// interpolating linearly between the closest ranks.
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) <= 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo, hi := int(math.Floor(rank)), int(math.Ceil(rank))
+	if lo < 0 {
+		return sorted[0]
+	}
+	if hi >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+// Median returns the median of sorted values.
+func Median(sorted []float64) float64 {
+	return Percentile(sorted, 50)
+}
+
+// MAD returns the median absolute deviation of sorted values from their
+// median.
+func MAD(sorted []float64) float64 {
+	median := Median(sorted)
+	deviations := make([]float64, len(sorted))
+	for i, v := range sorted {
+		deviations[i] = math.Abs(v - median)
+	}
+	sort.Float64s(deviations)
+	return Median(deviations)
+}
+
+// A Bin is one bar of a histogram: the number of values in [Low, High).
+// The last bin of a histogram includes its High.
+type Bin struct {
+	Low, High float64
+	Count     int
+}
+
+// Histogram sorts sorted values into n equal-width bins spanning their
+// range. If every value is the same, there's just one bin.
+func Histogram(sorted []float64, n int) []Bin {
+	if len(sorted) <= 0 || n <= 0 {
+		return []Bin{}
+	}
+	min, max := sorted[0], sorted[len(sorted)-1]
+	if min == max {
+		return []Bin{Bin{min, max, len(sorted)}}
+	}
+	width := (max - min) / float64(n)
+	bins := make([]Bin, n)
+	for i := range bins {
+		bins[i].Low = min + float64(i)*width
+		bins[i].High = min + float64(i+1)*width
+	}
+	bins[n-1].High = max
+	for _, v := range sorted {
+		i := int((v - min) / width)
+		if i >= n {
+			i = n - 1
 		}
+		bins[i].Count++
 	}
-	log.Printf(
-		"%d >10 standard deviations (%d) from minimum %d",
-		score,
-		stats.StandardDeviation,
-		stats.Minimum,
-	)
-	return 10
+	return bins
 }
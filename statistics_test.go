@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGatherSkipsMissingScores(t *testing.T) {
+	reviews := Reviews{
+		1: Review{ID: 1, Scores: map[string]float64{"Foo": 2}},
+		2: Review{ID: 2, Scores: map[string]float64{"Foo": 4}},
+		3: Review{ID: 3, Scores: map[string]float64{"Foo": 4, "Bar": 1}},
+		4: Review{ID: 4, Scores: map[string]float64{"Foo": 5}},
+		5: Review{ID: 5, Scores: map[string]float64{}},
+	}
+	s := Gather(reviews, "Foo")
+	if s.Instances != 4 {
+		t.Errorf("got %d instances, expected 4", s.Instances)
+	}
+	// values 2 4 4 5: mean 3.75, variance (3.0625+0.0625+0.0625+1.5625)/4
+	got := fmt.Sprintf(
+		"%.4f %.4f %.4f %.4f %.4f %.4f",
+		s.Minimum, s.Maximum, s.Mean, s.Variance, s.Median, s.MAD,
+	)
+	if expected := "2.0000 5.0000 3.7500 1.1875 4.0000 0.5000"; got != expected {
+		t.Errorf("got %s, expected %s", got, expected)
+	}
+
+	if s := Gather(reviews, "Baz"); s.Instances != 0 || s.Mean != 0 || s.StandardDeviation != 0 {
+		t.Errorf("no scores: got %+v", s)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+	for p, expected := range map[float64]float64{
+		0:   1,
+		25:  2,
+		50:  3,
+		90:  4.6,
+		100: 5,
+	} {
+		if got := Percentile(sorted, p); fmt.Sprintf("%.4f", got) != fmt.Sprintf("%.4f", expected) {
+			t.Errorf("p%v: got %v, expected %v", p, got, expected)
+		}
+	}
+	if got := Median([]float64{1, 2, 3, 10}); got != 2.5 {
+		t.Errorf("even median: got %v, expected 2.5", got)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	bins := Histogram([]float64{0, 1, 2, 5, 9, 10}, 5)
+	counts := []int{}
+	for _, bin := range bins {
+		counts = append(counts, bin.Count)
+	}
+	if fmt.Sprint(counts) != "[2 1 1 0 2]" {
+		t.Errorf("got %v, expected [2 1 1 0 2]", counts)
+	}
+	if bins[4].High != 10 {
+		t.Errorf("last bin ends at %v, expected 10", bins[4].High)
+	}
+	if bins := Histogram([]float64{3, 3}, 5); len(bins) != 1 || bins[0].Count != 2 {
+		t.Errorf("constant values: got %v", bins)
+	}
+	if bins := Histogram([]float64{}, 5); len(bins) != 0 {
+		t.Errorf("no values: got %v", bins)
+	}
+}
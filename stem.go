@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StemmerVersion must be incremented whenever Stem changes, since the
+// lexicon and phrase indexes depend on it.
+const StemmerVersion = 1
+
+// Stem returns the stem of a lowercase English word, per Porter's algorithm,
+// so that inflected and derived forms share a stem: "shimmers" and
+// "shimmering" both stem to "shimmer", "hypnotic" and "hypnotically" to
+// "hypnot". Stems aren't necessarily words. Words of anything but the
+// letters a-z, like "off-key" or "naïve", are returned unchanged.
+// http://tartarus.org/martin/PorterStemmer/def.txt
+func Stem(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	p := &porter{b: []byte(word)}
+	p.step1ab()
+	if len(p.b) > 1 {
+		p.step1c()
+		p.step2()
+		p.step3()
+		p.step4()
+		p.step5()
+	}
+	return string(p.b)
+}
+
+// porter is a word partway through stemming. After a successful ends, b[:j+1]
+// is the word without the suffix.
+type porter struct {
+	b []byte
+	j int
+}
+
+// cons reports whether b[i] is a consonant. Y is a consonant at the start
+// of a word, or after a vowel.
+func (p *porter) cons(i int) bool {
+	switch p.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !p.cons(i-1)
+	}
+	return true
+}
+
+// m measures b[:j+1]: the number of vowel-consonant sequences in it.
+func (p *porter) m() int {
+	n, i := 0, 0
+	for ; i <= p.j && p.cons(i); i++ {
+	}
+	for i <= p.j {
+		for ; i <= p.j && !p.cons(i); i++ {
+		}
+		if i > p.j {
+			break
+		}
+		for ; i <= p.j && p.cons(i); i++ {
+		}
+		n++
+	}
+	return n
+}
+
+// vowelInStem reports whether b[:j+1] contains a vowel.
+func (p *porter) vowelInStem() bool {
+	for i := 0; i <= p.j; i++ {
+		if !p.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleC reports whether b[i-1:i+1] is a double consonant.
+func (p *porter) doubleC(i int) bool {
+	return i >= 1 && p.b[i] == p.b[i-1] && p.cons(i)
+}
+
+// cvc reports whether b[i-2:i+1] is consonant-vowel-consonant, and the last
+// consonant isn't w, x or y. It marks a short syllable, as in "hop".
+func (p *porter) cvc(i int) bool {
+	if i < 2 || !p.cons(i) || p.cons(i-1) || !p.cons(i-2) {
+		return false
+	}
+	switch p.b[i] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func (p *porter) ends(suffix string) bool {
+	if !strings.HasSuffix(string(p.b), suffix) {
+		return false
+	}
+	p.j = len(p.b) - len(suffix) - 1
+	return true
+}
+
+func (p *porter) setTo(s string) { p.b = append(p.b[:p.j+1], s...) }
+func (p *porter) chop()          { p.b = p.b[:len(p.b)-1] }
+func (p *porter) last() int      { return len(p.b) - 1 }
+
+// replace applies the first rule whose suffix the word ends with, if what
+// precedes the suffix measures more than min.
+func (p *porter) replace(rules [][2]string, min int) {
+	for _, rule := range rules {
+		if p.ends(rule[0]) {
+			if p.m() > min {
+				p.setTo(rule[1])
+			}
+			return
+		}
+	}
+}
+
+// step1ab removes plurals, -ed and -ing.
+func (p *porter) step1ab() {
+	if p.b[p.last()] == 's' {
+		switch {
+		case p.ends("sses"):
+			p.setTo("ss")
+		case p.ends("ies"):
+			p.setTo("i")
+		case p.b[p.last()-1] != 's':
+			p.chop()
+		}
+	}
+	if p.ends("eed") {
+		if p.m() > 0 {
+			p.chop()
+		}
+		return
+	}
+	if !(p.ends("ed") || p.ends("ing")) || !p.vowelInStem() {
+		return
+	}
+	p.b = p.b[:p.j+1]
+	switch {
+	case p.ends("at"):
+		p.setTo("ate")
+	case p.ends("bl"):
+		p.setTo("ble")
+	case p.ends("iz"):
+		p.setTo("ize")
+	case p.doubleC(p.last()):
+		switch p.b[p.last()] {
+		case 'l', 's', 'z':
+		default:
+			p.chop()
+		}
+	default:
+		p.j = p.last()
+		if p.m() == 1 && p.cvc(p.last()) {
+			p.b = append(p.b, 'e')
+		}
+	}
+}
+
+// step1c turns a final y into i, if there's another vowel.
+func (p *porter) step1c() {
+	if p.ends("y") && p.vowelInStem() {
+		p.b[p.last()] = 'i'
+	}
+}
+
+var porterStep2 = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+var porterStep3 = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+var porterStep4 = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+// step2 maps double suffixes to single ones: -ization to -ize, and so on.
+func (p *porter) step2() { p.replace(porterStep2, 0) }
+
+// step3 deals with -ic-, -full, -ness, etc.
+func (p *porter) step3() { p.replace(porterStep3, 0) }
+
+// step4 removes -ant, -ence, etc., from words of more than one syllable.
+func (p *porter) step4() {
+	for _, suffix := range porterStep4 {
+		if p.ends(suffix) {
+			if suffix == "ion" && (p.j < 0 || (p.b[p.j] != 's' && p.b[p.j] != 't')) {
+				return
+			}
+			if p.m() > 1 {
+				p.b = p.b[:p.j+1]
+			}
+			return
+		}
+	}
+}
+
+// step5 removes a final -e, and reduces a final -ll to -l, from longer
+// words.
+func (p *porter) step5() {
+	p.j = p.last()
+	if p.b[p.last()] == 'e' {
+		p.j--
+		if m := p.m(); m > 1 || (m == 1 && !p.cvc(p.last()-1)) {
+			p.chop()
+		}
+	}
+	p.j = p.last()
+	if p.b[p.last()] == 'l' && p.doubleC(p.last()) && p.m() > 1 {
+		p.chop()
+	}
+}
+
+//
+//
+//
+
+// A Lexicon weighs words by their stems, so that one entry covers every
+// form of a word. Forms that shouldn't be stemmed, usually because their
+// stem is a common word in its own right ("playful", "play"), are listed
+// exactly, and override any stem. A form listed with weight 0 isn't in the
+// lexicon.
+type Lexicon struct {
+	stems map[string]int
+	forms map[string]int
+}
+
+// NewLexicon keys the given words by their stems. Words that share a stem
+// must share a weight.
+func NewLexicon(words, forms map[string]int) (Lexicon, error) {
+	l := Lexicon{stems: map[string]int{}, forms: map[string]int{}}
+	for word, weight := range words {
+		stem := Stem(word)
+		if prev, ok := l.stems[stem]; ok && prev != weight {
+			return Lexicon{}, fmt.Errorf("%q stems to %q, already weighted %d, not %d", word, stem, prev, weight)
+		}
+		l.stems[stem] = weight
+	}
+	for form, weight := range forms {
+		l.forms[form] = weight
+	}
+	return l, nil
+}
+
+func mustLexicon(words, forms map[string]int) Lexicon {
+	l, err := NewLexicon(words, forms)
+	if err != nil {
+		panic(err)
+	}
+	return l
+}
+
+// Weight returns the weight of word, and whether it's in the lexicon.
+func (l Lexicon) Weight(word string) (int, bool) {
+	if weight, ok := l.forms[word]; ok {
+		return weight, weight != 0
+	}
+	weight, ok := l.stems[Stem(word)]
+	return weight, ok
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	for word, expected := range map[string]string{
+		"caresses":        "caress",
+		"ponies":          "poni",
+		"ties":            "ti",
+		"caress":          "caress",
+		"cats":            "cat",
+		"feed":            "feed",
+		"agreed":          "agre",
+		"plastered":       "plaster",
+		"bled":            "bled",
+		"motoring":        "motor",
+		"sing":            "sing",
+		"hopping":         "hop",
+		"falling":         "fall",
+		"hissing":         "hiss",
+		"fizzed":          "fizz",
+		"filing":          "file",
+		"happy":           "happi",
+		"sky":             "sky",
+		"relational":      "relat",
+		"generalizations": "gener",
+		"oscillators":     "oscil",
+		"hopeful":         "hope",
+		"goodness":        "good",
+		"adjustment":      "adjust",
+		"controlling":     "control",
+		"shimmers":        "shimmer",
+		"shimmering":      "shimmer",
+		"hypnotic":        "hypnot",
+		"hypnotically":    "hypnot",
+		"as":              "as",
+		"off-key":         "off-key",
+		"naïve":           "naïve",
+	} {
+		if got := Stem(word); got != expected {
+			t.Errorf("%q: got %q, expected %q", word, got, expected)
+		}
+	}
+}
+
+func TestLexicon(t *testing.T) {
+	for _, tuple := range []struct {
+		word     string
+		expected int
+	}{
+		{"croon", 5},
+		{"croons", 5},
+		{"crooned", 5},
+		{"shimmers", 5},
+		{"hypnotically", 4},
+		{"glows", 1},
+		{"glowing", 3}, // exact form overrides its stem
+		{"cutting", 1},
+		{"cut", 0}, // "cutting" is only weighed in that form
+		{"affecting", 1},
+		{"affect", 0},
+		{"affects", 0},
+		{"affected", 0},
+		{"pound", 0},
+		{"pounds", 0},
+		{"emotion", 0},
+		{"emotive", 0},
+		{"assure", 0},
+		{"dominant", 0},
+		{"play", 0},
+		{"organ", 0},
+		{"guitar", 0},
+	} {
+		if got, _ := PitchformulaLexicon.Weight(tuple.word); got != tuple.expected {
+			t.Errorf("%q: got %d, expected %d", tuple.word, got, tuple.expected)
+		}
+	}
+
+	// Every headword still weighs what it did
+	for word, weight := range PitchformulaWords {
+		if _, ok := PitchformulaForms[word]; ok {
+			continue
+		}
+		if got, ok := PitchformulaLexicon.Weight(word); !ok || got != weight {
+			t.Errorf("%q: got %d, expected %d", word, got, weight)
+		}
+	}
+
+	if _, err := NewLexicon(map[string]int{"croon": 5, "croons": 1}, map[string]int{}); err == nil {
+		t.Errorf("expected error for conflicting weights of one stem")
+	}
+	l, err := NewLexicon(map[string]int{"glow": 1}, map[string]int{"glowed": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.Weight("glowed"); ok {
+		t.Errorf("expected a form weighted 0 to be left out")
+	}
+}
+
+func TestPitchformulaityInflections(t *testing.T) {
+	// None of these forms were in the lexicon when it was matched exactly
+	r := Review{Body: "<p>It shimmers hypnotically, and he crooned and wailed.</p>"}
+	score, evidence := ExplainPitchformulaity(r)
+	if expected := 5.0 + 4 + 5 + 3; score != expected {
+		t.Errorf("got %v, expected %v (%+v)", score, expected, evidence)
+	}
+
+	// Forms that share a stem count once each, not once per variant
+	r = Review{Body: "croon croons crooning. Subtle, subtly, subtlety."}
+	score, evidence = ExplainPitchformulaity(r)
+	if expected := 5.0*3 + 2*3; score != expected {
+		t.Errorf("got %v, expected %v", score, expected)
+	}
+	if len(evidence) != 6 {
+		t.Errorf("got %d pieces of evidence, expected 6", len(evidence))
+	}
+}
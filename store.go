@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// A Store persists reviews and scores. Inserts are upserts, and each call is
+// atomic.
+type Store interface {
+	SchemaVersion() (int, error)
+	Migrate() (from int, to int, err error)
+
+	SelectReviews(ids []int) (Reviews, error)
+	SelectAllReviews() (Reviews, error)
+	IterateReviews() ReviewIterator
+	SelectBodys(ids []int) (map[int]string, error)
+	InsertReviews(reviews Reviews) error
+	InsertReviewScores(scores map[int]map[string]float64, overwrite bool) error
+
+	InsertAuthorScores(scores map[string]map[string]AuthorScore, overwrite bool) error
+	PruneAuthors() error
+	SelectAllAuthorScores() (map[string]map[string]AuthorScore, error)
+	AuthorScoresComputedAt() (time.Time, error)
+
+	Close() error
+}
+
+// OpenStore opens the Store for the given driver: "sqlite3" (source is a
+// filename), "postgres" (source is a DSN) or "memory" (source is ignored).
+func OpenStore(driver, source string) (Store, error) {
+	switch driver {
+	case "sqlite3":
+		return GetDB(source)
+	case "postgres":
+		return GetPostgresDB(source)
+	case "memory":
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown store driver %q", driver)
+}
+
+//
+//
+//
+
+func (db *DB) SchemaVersion() (int, error)        { return SchemaVersion(db) }
+func (db *DB) Migrate() (int, int, error)         { return Migrate(db) }
+func (db *DB) SelectAllReviews() (Reviews, error) { return SelectAllReviews(db) }
+
+func (db *DB) IterateReviews() ReviewIterator { return IterateReviews(db) }
+
+func (db *DB) SelectReviews(ids []int) (Reviews, error) {
+	return SelectReviews(db, ids)
+}
+
+func (db *DB) SelectBodys(ids []int) (map[int]string, error) {
+	return SelectBodys(db, ids)
+}
+
+func (db *DB) InsertReviews(reviews Reviews) error {
+	return InsertReviews(db, reviews)
+}
+
+func (db *DB) InsertReviewScores(scores map[int]map[string]float64, overwrite bool) error {
+	return InsertReviewScores(db, scores, overwrite)
+}
+
+func (db *DB) InsertAuthorScores(scores map[string]map[string]AuthorScore, overwrite bool) error {
+	return InsertAuthorScores(db, scores, overwrite)
+}
+
+func (db *DB) PruneAuthors() error { return PruneAuthors(db) }
+
+func (db *DB) SelectAllAuthorScores() (map[string]map[string]AuthorScore, error) {
+	return SelectAllAuthorScores(db)
+}
+
+func (db *DB) AuthorScoresComputedAt() (time.Time, error) {
+	return AuthorScoresComputedAt(db)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Every Store must pass the conformance suite.
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func() Store { return NewMemoryStore() })
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func() Store {
+		os.Remove("testing.db")
+		db, err := GetDB("testing.db")
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		return db
+	})
+}
+
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("PITCHDEX_POSTGRES_DSN")
+	if dsn == "" {
+		var stop func()
+		dsn, stop = startPostgres(t)
+		defer stop()
+	}
+	testStore(t, func() Store {
+		db, err := GetPostgresDB(dsn)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		for _, statement := range []string{
+			"DROP SCHEMA public CASCADE",
+			"CREATE SCHEMA public",
+		} {
+			if _, err := db.Exec(statement); err != nil {
+				t.Fatalf("%s: %s", statement, err)
+			}
+		}
+		return db
+	})
+}
+
+// postgresRequired reports whether the PostgreSQL tests must run rather than
+// skip: in CI, or wherever PITCHDEX_REQUIRE_POSTGRES is set.
+func postgresRequired() bool {
+	return os.Getenv("CI") != "" || os.Getenv("PITCHDEX_REQUIRE_POSTGRES") != ""
+}
+
+// startPostgres starts a throwaway PostgreSQL server, if one is installed.
+func startPostgres(t *testing.T) (string, func()) {
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		matches, _ := filepath.Glob("/usr/lib/postgresql/*/bin/initdb")
+		if len(matches) <= 0 {
+			if postgresRequired() {
+				t.Fatal("PostgreSQL not installed, and PITCHDEX_POSTGRES_DSN not set")
+			}
+			t.Skip("PostgreSQL not installed, and PITCHDEX_POSTGRES_DSN not set")
+		}
+		initdb = matches[len(matches)-1]
+	}
+	pgCtl := filepath.Join(filepath.Dir(initdb), "pg_ctl")
+
+	dir, err := ioutil.TempDir("", "pitchdex-postgres")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(initdb, "-D", data, "-U", "pitchdex", "-A", "trust").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("initdb: %s: %s", err, out)
+	}
+	if out, err := exec.Command(
+		pgCtl, "start", "-w", "-D", data, "-l", filepath.Join(dir, "log"),
+		"-o", fmt.Sprintf("-h 127.0.0.1 -p %d -k %s", port, dir),
+	).CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("pg_ctl start: %s: %s", err, out)
+	}
+	stop := func() {
+		exec.Command(pgCtl, "stop", "-m", "immediate", "-D", data).Run()
+		os.RemoveAll(dir)
+	}
+	dsn := fmt.Sprintf("host=127.0.0.1 port=%d user=pitchdex dbname=postgres sslmode=disable", port)
+	return dsn, stop
+}
+
+func testStore(t *testing.T, newStore func() Store) {
+	for name, f := range map[string]func(*testing.T, Store){
+		"Migrate":      testStoreMigrate,
+		"Reviews":      testStoreReviews,
+		"Upsert":       testStoreUpsert,
+		"Iterate":      testStoreIterate,
+		"ReviewScores": testStoreReviewScores,
+		"AuthorScores": testStoreAuthorScores,
+		"PruneAuthors": testStorePruneAuthors,
+		"Provenance":   testStoreProvenance,
+	} {
+		s := newStore()
+		if _, _, err := s.Migrate(); err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		f(t, s)
+		s.Close()
+	}
+}
+
+func testStoreMigrate(t *testing.T, s Store) {
+	from, to, err := s.Migrate()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if from != TargetSchemaVersion() || to != TargetSchemaVersion() {
+		t.Errorf("migrated %d -> %d, expected no-op at %d", from, to, TargetSchemaVersion())
+	}
+	if v, err := s.SchemaVersion(); err != nil || v != TargetSchemaVersion() {
+		t.Errorf("got version %d (%v), expected %d", v, err, TargetSchemaVersion())
+	}
+}
+
+func testStoreReviews(t *testing.T, s Store) {
+	r1 := Review{
+		ID:           1,
+		Author:       "Joe Reviewer",
+		Body:         "<p>Lush.</p>",
+		Permalink:    "1-some-album",
+		Artist:       "Some Band",
+		Album:        "Some Album",
+		Label:        "Some Label",
+		ReleaseYear:  2012,
+		Published:    time.Date(2012, 10, 2, 0, 0, 0, 0, time.UTC),
+		Rating:       8.4,
+		BestNewMusic: true,
+		Scores:       map[string]float64{"Foo": 7, "Bar": 1},
+	}
+	r2 := Review{ID: 2, Author: "Frank Reviewer", Body: "Two.", Scores: map[string]float64{}}
+	if err := s.InsertReviews(Reviews{1: r1, 2: r2}); err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	reviews, err := s.SelectAllReviews()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(reviews) != 2 {
+		t.Fatalf("got %d reviews, expected 2", len(reviews))
+	}
+	got := reviews[1]
+	if got.Author != r1.Author || got.Body != r1.Body || got.Permalink != r1.Permalink ||
+		got.Artist != r1.Artist || got.Album != r1.Album || got.Label != r1.Label ||
+		got.ReleaseYear != r1.ReleaseYear || !got.Published.Equal(r1.Published) ||
+		got.Rating != r1.Rating || got.BestNewMusic != r1.BestNewMusic {
+		t.Errorf("got %+v, expected %+v", got, r1)
+	}
+	if len(got.Scores) != 2 || got.Scores["Foo"] != 7 || got.Scores["Bar"] != 1 {
+		t.Errorf("got scores %v, expected %v", got.Scores, r1.Scores)
+	}
+
+	some, err := s.SelectReviews([]int{2, 3})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, ok := some[2]; len(some) != 1 || !ok {
+		t.Errorf("got %v, expected only review 2", some)
+	}
+
+	bodys, err := s.SelectBodys([]int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(bodys) != 2 || bodys[1] != r1.Body || bodys[2] != r2.Body {
+		t.Errorf("got bodys %v", bodys)
+	}
+}
+
+func testStoreUpsert(t *testing.T, s Store) {
+	r := Review{ID: 1, Author: "A", Body: "Before.", Scores: map[string]float64{"Foo": 1}}
+	if err := s.InsertReviews(Reviews{1: r}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	r = Review{ID: 1, Author: "B", Body: "After.", Scores: map[string]float64{"Bar": 2}}
+	if err := s.InsertReviews(Reviews{1: r}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	reviews, err := s.SelectAllReviews()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	got := reviews[1]
+	if len(reviews) != 1 || got.Author != "B" || got.Body != "After." {
+		t.Errorf("got %v, expected one updated review", reviews)
+	}
+	if got.Scores["Foo"] != 1 || got.Scores["Bar"] != 2 {
+		t.Errorf("got scores %v, expected Foo 1 and Bar 2", got.Scores)
+	}
+}
+
+func testStoreReviewScores(t *testing.T, s Store) {
+	r := Review{ID: 1, Author: "A", Body: "One.", Scores: map[string]float64{"Foo": 1}}
+	if err := s.InsertReviews(Reviews{1: r}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := s.InsertReviewScores(map[int]map[string]float64{1: {"Foo": 2, "Bar": 3}}, false); err != nil {
+		t.Fatalf("%s", err)
+	}
+	reviews, _ := s.SelectReviews([]int{1})
+	if reviews[1].Scores["Foo"] != 1 || reviews[1].Scores["Bar"] != 3 {
+		t.Errorf("without overwrite: got %v, expected Foo 1 and Bar 3", reviews[1].Scores)
+	}
+	if err := s.InsertReviewScores(map[int]map[string]float64{1: {"Foo": 2}}, true); err != nil {
+		t.Fatalf("%s", err)
+	}
+	reviews, _ = s.SelectReviews([]int{1})
+	if reviews[1].Scores["Foo"] != 2 {
+		t.Errorf("with overwrite: got %v, expected Foo 2", reviews[1].Scores)
+	}
+}
+
+func testStoreAuthorScores(t *testing.T, s Store) {
+	if computedAt, err := s.AuthorScoresComputedAt(); err != nil || !computedAt.IsZero() {
+		t.Errorf("got computed-at %v (%v), expected zero", computedAt, err)
+	}
+	before := time.Now().Add(-time.Second)
+	authors := map[string]map[string]AuthorScore{
+		"A": {"Reviews": exactly(3), "Foo": AuthorScore{7, 8, 6.5, 9.5}},
+		"B": {"Reviews": exactly(1), "Foo": exactly(2)},
+	}
+	if err := s.InsertAuthorScores(authors, true); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := s.InsertAuthorScores(map[string]map[string]AuthorScore{"B": {"Foo": exactly(4)}}, false); err != nil {
+		t.Fatalf("%s", err)
+	}
+	got, err := s.SelectAllAuthorScores()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if len(got) != 2 || got["A"]["Reviews"] != exactly(3) || got["A"]["Foo"] != authors["A"]["Foo"] || got["B"]["Foo"] != exactly(2) {
+		t.Errorf("got %v, expected %v", got, authors)
+	}
+	if computedAt, err := s.AuthorScoresComputedAt(); err != nil || computedAt.Before(before) {
+		t.Errorf("got computed-at %v (%v), expected after %v", computedAt, err, before)
+	}
+}
+
+func testStoreIterate(t *testing.T, s Store) {
+	reviews := Reviews{}
+	for _, id := range []int{5, 3, 9, 1} {
+		reviews[id] = Review{ID: id, Author: "A", Body: "Body.", Scores: map[string]float64{"Foo": float64(id)}}
+	}
+	if err := s.InsertReviews(reviews); err != nil {
+		t.Fatalf("%s", err)
+	}
+	got := []int{}
+	it := s.IterateReviews()
+	for it.Next() {
+		review := it.Review()
+		if review.Scores["Foo"] != float64(review.ID) {
+			t.Errorf("review %d: got score %.1f", review.ID, review.Scores["Foo"])
+		}
+		got = append(got, review.ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if fmt.Sprint(got) != "[1 3 5 9]" {
+		t.Errorf("got IDs %v, expected [1 3 5 9]", got)
+	}
+}
+
+func testStorePruneAuthors(t *testing.T, s Store) {
+	if err := s.InsertReviews(Reviews{1: Review{ID: 1, Author: "A", Body: "One."}}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := s.InsertAuthorScores(map[string]map[string]AuthorScore{
+		"A": {"Reviews": exactly(1)},
+		"B": {"Reviews": exactly(1)},
+	}, true); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := s.PruneAuthors(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	got, err := s.SelectAllAuthorScores()
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if _, ok := got["A"]; len(got) != 1 || !ok {
+		t.Errorf("got %v, expected only A", got)
+	}
+}
+
+func testStoreProvenance(t *testing.T, s Store) {
+	p := Provenance{2, "english-1@sha256:0123", "0123456789abcdef"}
+	r := Review{
+		ID:         1,
+		Author:     "A",
+		Body:       "One.",
+		Scores:     map[string]float64{"Foo": 1, "Bar": 2},
+		Provenance: map[string]Provenance{"Foo": p},
+	}
+	if err := s.InsertReviews(Reviews{1: r}); err != nil {
+		t.Fatalf("%s", err)
+	}
+	reviews, err := s.SelectReviews([]int{1})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got := reviews[1].Provenance; got["Foo"] != p || got["Bar"] != (Provenance{}) {
+		t.Errorf("got %v, expected Foo %v and Bar unrecorded", got, p)
+	}
+	if err := s.InsertReviewScores(map[int]map[string]float64{1: {"Foo": 3}}, true); err != nil {
+		t.Fatalf("%s", err)
+	}
+	reviews, _ = s.SelectReviews([]int{1})
+	if got := reviews[1].Provenance["Foo"]; got != (Provenance{}) {
+		t.Errorf("got %v, expected a score without provenance to be unrecorded", got)
+	}
+}
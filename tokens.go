@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"github.com/peterbourgon/exp-html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenizerVersion must be incremented whenever Tokens or Sentences split
+// text differently, since every score depends on them.
+const TokenizerVersion = 1
+
+// A Token is a word, and where it appears in the body it came from.
+type Token struct {
+	Word       string // lowercased, with typographic apostrophes and hyphens folded to ASCII
+	Start, End int    // byte offsets into the body, including any entities
+}
+
+// Tokens splits an HTML body into words. Words are runs of letters, marks
+// and digits; they run on through an apostrophe between letters ("don't"),
+// a hyphen between letters or digits ("lo-fi", "24-track"), and a period
+// or comma between digits ("7.1", "1,000"). Everything else, whitespace and
+// punctuation of every kind, separates words.
+func Tokens(body string) []Token {
+	z := html.NewTokenizer(bytes.NewBufferString(body))
+	tokens, offset := []Token{}, 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return tokens
+		}
+		raw := z.Raw()
+		if tt == html.TextToken {
+			tokens = append(tokens, wordTokens(decodeText(string(raw), offset))...)
+		}
+		offset += len(raw)
+	}
+}
+
+// TextWords splits plain, already decoded text into words, as Tokens does
+// HTML: "<" and "&lt;" in it are just characters.
+func TextWords(text string) []string {
+	chars := []char{}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		chars = append(chars, char{r, i, i + size})
+		i += size
+	}
+	words := []string{}
+	for _, tok := range wordTokens(chars) {
+		words = append(words, tok.Word)
+	}
+	return words
+}
+
+// A char is a decoded rune, and the span of the body it was decoded from.
+type char struct {
+	r          rune
+	start, end int
+}
+
+// decodeText decodes raw HTML text, which begins at offset in the body,
+// resolving character references.
+func decodeText(raw string, offset int) []char {
+	chars := []char{}
+	for i := 0; i < len(raw); {
+		if raw[i] == '&' {
+			if j := strings.IndexByte(raw[i:], ';'); j > 0 && j <= 32 {
+				ref := raw[i : i+j+1]
+				if s := html.UnescapeString(ref); s != ref {
+					for _, r := range s {
+						chars = append(chars, char{r, offset + i, offset + i + j + 1})
+					}
+					i += j + 1
+					continue
+				}
+			}
+		}
+		r, size := utf8.DecodeRuneInString(raw[i:])
+		chars = append(chars, char{r, offset + i, offset + i + size})
+		i += size
+	}
+	return chars
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func isApostrophe(r rune) bool { return r == '\'' || r == '’' || r == 'ʼ' }
+func isHyphen(r rune) bool     { return r == '-' || r == '‐' || r == '‑' }
+
+// joins reports whether the non-word rune at chars[k] joins the words on
+// either side of it.
+func joins(chars []char, k int) bool {
+	if k <= 0 || k+1 >= len(chars) {
+		return false
+	}
+	prev, r, next := chars[k-1].r, chars[k].r, chars[k+1].r
+	switch {
+	case isApostrophe(r):
+		return unicode.IsLetter(prev) && unicode.IsLetter(next)
+	case isHyphen(r):
+		return isWordRune(prev) && isWordRune(next)
+	case r == '.' || r == ',':
+		return unicode.IsDigit(prev) && unicode.IsDigit(next)
+	}
+	return false
+}
+
+func wordTokens(chars []char) []Token {
+	tokens := []Token{}
+	start := -1
+	end := func(k int) {
+		if start >= 0 {
+			tokens = append(tokens, Token{
+				Word:  foldWord(chars[start:k]),
+				Start: chars[start].start,
+				End:   chars[k-1].end,
+			})
+		}
+		start = -1
+	}
+	for k, c := range chars {
+		switch {
+		case isWordRune(c.r):
+			if start < 0 {
+				start = k
+			}
+		case start >= 0 && joins(chars, k):
+		case start >= 0 && unicode.Is(unicode.Cf, c.r):
+			// soft hyphens and zero-width joiners, inside a word
+		default:
+			end(k)
+		}
+	}
+	end(len(chars))
+	return tokens
+}
+
+func foldWord(chars []char) string {
+	runes := make([]rune, 0, len(chars))
+	for _, c := range chars {
+		switch {
+		case isApostrophe(c.r):
+			runes = append(runes, '\'')
+		case isHyphen(c.r):
+			runes = append(runes, '-')
+		case unicode.Is(unicode.Cf, c.r):
+		default:
+			runes = append(runes, unicode.ToLower(c.r))
+		}
+	}
+	return string(runes)
+}
@@ -4,64 +4,128 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Review struct {
-	ID        int
-	Author    string
-	Body      string
-	Permalink string
-	Scores    map[string]int
+	ID           int
+	Author       string // the byline, as credited; see Authors
+	Body         string
+	Permalink    string
+	Artist       string
+	Album        string
+	Label        string
+	ReleaseYear  int
+	Published    time.Time // zero if unknown
+	Rating       float64   // Pitchfork's own, 0.0-10.0
+	BestNewMusic bool
+	Scores       map[string]float64
+	Provenance   map[string]Provenance // of each score, by name
 }
 
 type Reviews map[int]Review
 
 type JSONReview struct {
-	Author    string `json:"reviewers"`
-	Body      string `json:"editorial"`
-	Permalink string `json:"key"`
+	Author       string  `json:"reviewers"`
+	Body         string  `json:"editorial"`
+	Permalink    string  `json:"key"`
+	Artist       string  `json:"artist"`
+	Album        string  `json:"album"`
+	Label        string  `json:"label"`
+	ReleaseYear  int     `json:"year"`
+	Published    string  `json:"pub_date"` // YYYY-MM-DD
+	Rating       float64 `json:"score"`
+	BestNewMusic bool    `json:"best_new_music"`
 }
 
-type JSONReviews []JSONReview
+const DateFormat = "2006-01-02"
 
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(DateFormat, s)
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DateFormat)
+}
+
+// Review validates a JSONReview and converts it to a Review.
+func (jr JSONReview) Review() (Review, error) {
+	id, err := strconv.ParseInt(strings.Split(jr.Permalink, "-")[0], 10, 64)
+	if err != nil {
+		return Review{}, fmt.Errorf("%s: %s", jr.Permalink, err)
+	}
+	if len(ParseReviewers(jr.Author)) <= 0 || jr.Body == "" {
+		return Review{}, fmt.Errorf(
+			"%s: author %dB, body %dB",
+			jr.Permalink,
+			len(jr.Author),
+			len(jr.Body),
+		)
+	}
+	published, err := parseDate(jr.Published)
+	if err != nil {
+		return Review{}, fmt.Errorf("%s: %s", jr.Permalink, err)
+	}
+	return Review{
+		ID:           int(id),
+		Author:       jr.Author,
+		Body:         jr.Body,
+		Permalink:    jr.Permalink,
+		Artist:       jr.Artist,
+		Album:        jr.Album,
+		Label:        jr.Label,
+		ReleaseYear:  jr.ReleaseYear,
+		Published:    published,
+		Rating:       jr.Rating,
+		BestNewMusic: jr.BestNewMusic,
+		Scores:       map[string]float64{},
+		Provenance:   map[string]Provenance{},
+	}, nil
+}
+
+// ImportJSON reads every review in filename into r, stopping at the first
+// bad record. Use an Importer to skip bad records instead.
 func (r Reviews) ImportJSON(filename string, reimport bool) error {
-	f, err := os.Open(filename)
+	f, err := openImport(filename)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
-	jsonReviews := JSONReviews{}
-	if err := json.NewDecoder(f).Decode(&jsonReviews); err != nil {
+	rr, err := newRecordReader(f)
+	if err != nil {
 		return err
 	}
-	for _, jsonReview := range jsonReviews {
-		id, err := strconv.ParseInt(strings.Split(jsonReview.Permalink, "-")[0], 10, 64)
+	for {
+		raw, err := rr.Next()
+		if err == io.EOF {
+			return nil
+		}
 		if err != nil {
-			return fmt.Errorf("%s: %s", jsonReview.Permalink, err)
+			return err
 		}
-		if jsonReview.Author == "" || jsonReview.Body == "" {
-			return fmt.Errorf(
-				"%s: author %dB, body %dB",
-				jsonReview.Permalink,
-				len(jsonReview.Author),
-				len(jsonReview.Body),
-			)
+		var jsonReview JSONReview
+		if err := json.Unmarshal(raw, &jsonReview); err != nil {
+			return fmt.Errorf("record %d: %s", rr.Position(), err)
 		}
-		if _, ok := r[int(id)]; !ok || reimport {
-			r[int(id)] = Review{
-				ID:        int(id),
-				Author:    jsonReview.Author,
-				Body:      jsonReview.Body,
-				Permalink: jsonReview.Permalink,
-				Scores:    map[string]int{},
-			}
+		review, err := jsonReview.Review()
+		if err != nil {
+			return err
+		}
+		if _, ok := r[review.ID]; !ok || reimport {
+			r[review.ID] = review
 		}
 	}
-	return nil
 }
 
 type Filter func(Review) bool
@@ -77,28 +141,27 @@ func (r Reviews) By(f Filter) IDSlice {
 	return matching
 }
 
+// AuthorCount returns the number of reviews each author is credited on.
 func (r Reviews) AuthorCount() map[string]int {
 	m := map[string]int{}
 	for _, review := range r {
-		if _, ok := m[review.Author]; ok {
-			m[review.Author]++
-		} else {
-			m[review.Author] = 1
+		for _, author := range review.Authors() {
+			m[author]++
 		}
 	}
 	return m
 }
 
-func (r Reviews) TotalScore(ids IDSlice, indexName string) int {
-	v := 0
+func (r Reviews) TotalScore(ids IDSlice, indexName string) float64 {
+	v := 0.0
 	for _, id := range ids {
 		v += r[id].Scores[indexName]
 	}
 	return v
 }
 
-func (r Reviews) AverageScore(ids IDSlice, indexName string) int {
-	return int(float64(r.TotalScore(ids, indexName)) / float64(len(ids)))
+func (r Reviews) AverageScore(ids IDSlice, indexName string) float64 {
+	return r.TotalScore(ids, indexName) / float64(len(ids))
 }
 
 //
@@ -135,7 +198,7 @@ func sortMapByValue(m map[string]int) PairList {
 //
 
 type IndexMap map[string]ScoringFunction
-type ScoringFunction func(Review) int
+type ScoringFunction func(Review) float64
 
 //
 //
@@ -143,26 +206,39 @@ type ScoringFunction func(Review) int
 
 type Dict map[string]struct{}
 
-func NewDict(filename string) Dict {
+// NewDict loads a Dict from a file of words, one per line. A dictionary that
+// can't be read, or that's empty, is an error: without one, every word
+// would be invented.
+func NewDict(filename string) (Dict, error) {
 	d := Dict{}
-	d.Load(filename)
-	return d
+	if err := d.Load(filename); err != nil {
+		return nil, err
+	}
+	if d.Count() <= 0 {
+		return nil, fmt.Errorf("%s: no words", filename)
+	}
+	return d, nil
 }
 
-func (d Dict) Load(filename string) {
+// Load adds the words in a file, one per line, to the Dict.
+func (d Dict) Load(filename string) error {
 	f, err := os.Open(filename)
 	if err != nil {
-		return
+		return err
 	}
 	defer f.Close()
-	r := bufio.NewReader(f)
-	for {
-		line, err := r.ReadString('\n')
-		if err != nil {
-			break
+	return d.Read(f)
+}
+
+// Read adds the words from r, one per line, to the Dict.
+func (d Dict) Read(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		if word := strings.TrimSpace(strings.ToLower(s.Text())); word != "" {
+			d[word] = struct{}{}
 		}
-		d[strings.TrimSpace(strings.ToLower(line))] = struct{}{}
 	}
+	return s.Err()
 }
 
 func (d Dict) Count() int {
@@ -0,0 +1,100 @@
+//go:build ignore
+
+// Scowl writes a word list for pitchdex from an unpacked SCOWL release
+// (http://wordlist.aspell.net/), one lowercase word per line, sorted: the
+// English and American words of every size up to -size, without
+// possessives, which the Words invented index recognizes itself. Extra word
+// lists, such as the vocabulary of music writing, may be given as
+// arguments, and are merged in.
+//
+//	go run words/scowl.go -scowl scowl-2020.12.07 -size 60 words/english-1.txt > words/english-2.txt
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	scowl *string = flag.String("scowl", "", "unpacked SCOWL release directory")
+	size  *int    = flag.Int("size", 60, "largest SCOWL size to include")
+)
+
+// sizes are SCOWL's list sizes, smallest (most common words) first.
+var sizes = []int{10, 20, 35, 40, 50, 55, 60, 70, 80, 95}
+
+func main() {
+	flag.Parse()
+	if *scowl == "" {
+		log.Fatalf("-scowl is required")
+	}
+	words := map[string]bool{}
+	for _, n := range sizes {
+		if n > *size {
+			break
+		}
+		for _, kind := range []string{"english", "american"} {
+			filename := filepath.Join(*scowl, "final", kind+"-words."+strconv.Itoa(n))
+			if err := read(filename, latin1, words); err != nil {
+				log.Fatalf("%s", err)
+			}
+		}
+	}
+	for _, filename := range flag.Args() {
+		if err := read(filename, func(s string) string { return s }, words); err != nil {
+			log.Fatalf("%s", err)
+		}
+	}
+
+	sorted := []string{}
+	for word, _ := range words {
+		sorted = append(sorted, word)
+	}
+	sort.Strings(sorted)
+	w := bufio.NewWriter(os.Stdout)
+	for _, word := range sorted {
+		fmt.Fprintln(w, word)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("%s", err)
+	}
+}
+
+// read adds the words of a file, one per line, decoded, to words.
+func read(filename string, decode func(string) string, words map[string]bool) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if word := strings.ToLower(strings.TrimSpace(decode(line))); word != "" && !strings.HasSuffix(word, "'s") {
+			words[word] = true
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// latin1 decodes ISO-8859-1, SCOWL's encoding.
+func latin1(s string) string {
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}